@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/shimron/stressingtool/chaincode"
+)
+
+var callCmd = &command{
+	name:    "call",
+	usage:   "[flags] <function> [args...]",
+	summary: "send one query or invoke and print the raw JSON-RPC or devops response",
+}
+
+func init() {
+	callCmd.run = runCall
+}
+
+func runCall(args []string) int {
+	fs := newFlagSet(callCmd)
+	url := fs.StringP("url", "u", "http://localhost:7050/chaincode", "chaincode REST endpoint")
+	ccid := fs.StringP("ccid", "c", "", "chaincode id")
+	isInvoke := fs.BoolP("invoke", "i", false, "invoke instead of query")
+	secureContext := fs.StringP("secure-context", "s", "", "enrollment id of a logged in user on a network with security")
+	devopsAddr := fs.StringP("devops", "g", "", "call the devops grpc service at this peer address instead of the REST endpoint, e.g. 127.0.0.1:7051")
+	fs.Parse(args)
+	if fs.NArg() == 0 || *ccid == "" {
+		fs.Usage()
+		return 2
+	}
+
+	if *devopsAddr != "" {
+		client, err := chaincode.NewDevopsClient(chaincode.DevopsConfig{})
+		if err != nil {
+			fmt.Printf("fail to create devops client:%v\n", err)
+			return 1
+		}
+		defer client.Close()
+		msg, err := client.QueryOrInvoke(*devopsAddr, *ccid, fs.Args(), *isInvoke, *secureContext)
+		if err != nil {
+			fmt.Printf("fail to %s:%v\n", mode(*isInvoke), err)
+			return 1
+		}
+		fmt.Println(msg)
+		return 0
+	}
+
+	b, err := chaincode.DefaultClient.Raw(*url, *ccid, fs.Args(), *isInvoke, *secureContext)
+	if err != nil {
+		fmt.Printf("fail to %s:%v\n", mode(*isInvoke), err)
+		return 1
+	}
+	fmt.Println(string(b))
+	return 0
+}
@@ -1,6 +1,7 @@
 package chaincode
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
@@ -8,58 +9,119 @@ import (
 	"strings"
 )
 
-//QueryOrInvoke ...
+//QueryOrInvoke return the txid of an invoke or the result message of a query
 func QueryOrInvoke(url string, ccid string, args []string, isInvoke bool) (string, error) {
+	return DefaultClient.QueryOrInvoke(url, ccid, args, isInvoke, "")
+}
+
+//Query return the result message of the query
+func Query(url string, ccid string, args []string) (string, error) {
+	return DefaultClient.Query(url, ccid, args, "")
+}
+
+//Invoke ...
+func Invoke(url string, ccid string, args []string) (string, error) {
+	return DefaultClient.Invoke(url, ccid, args, "")
+}
+
+//Raw send a query or invoke request and return the raw JSON-RPC response body
+func Raw(url string, ccid string, args []string, isInvoke bool) ([]byte, error) {
+	return DefaultClient.Raw(url, ccid, args, isInvoke, "")
+}
+
+//QueryOrInvoke return the txid of an invoke or the result message of a query,
+//secureContext is the enrollment id of a logged in user on a network with security, empty otherwise
+func (c *Client) QueryOrInvoke(url string, ccid string, args []string, isInvoke bool, secureContext string) (string, error) {
 	if isInvoke {
-		return Invoke(url, ccid, args)
+		return c.Invoke(url, ccid, args, secureContext)
 	}
-
-	err := Query(url, ccid, args)
-	return "", err
+	return c.Query(url, ccid, args, secureContext)
 }
 
-//Query ...
-func Query(url string, ccid string, args []string) error {
+//Query return the result message of the query
+func (c *Client) Query(url string, ccid string, args []string, secureContext string) (string, error) {
 
-	req := newJSONRPCRequest(false, ccid, args)
-	resp, err := post(url, req)
+	req := newJSONRPCRequest(false, ccid, args, secureContext)
+	resp, err := c.post(url, req)
 	if err != nil {
-		return nil
+		return "", err
 	}
-	if resp.Error != nil {
-		return errors.New(resp.Error.Message)
+	return resp.Result.Message, nil
+}
+
+//Invoke return the txid of the invoke
+func (c *Client) Invoke(url string, ccid string, args []string, secureContext string) (string, error) {
+	req := newJSONRPCRequest(true, ccid, args, secureContext)
+	resp, err := c.post(url, req)
+	if err != nil {
+		return "", err
 	}
-	return nil
+	return resp.Result.Message, nil
 }
 
-//Invoke ...
-func Invoke(url string, ccid string, args []string) (string, error) {
-	req := newJSONRPCRequest(true, ccid, args)
-	resp, err := post(url, req)
+//Raw send a query or invoke request and return the raw JSON-RPC response body
+func (c *Client) Raw(url string, ccid string, args []string, isInvoke bool, secureContext string) ([]byte, error) {
+	req := newJSONRPCRequest(isInvoke, ccid, args, secureContext)
+	b, _, err := c.postRaw(url, req)
+	return b, err
+}
+
+//post send the request, a JSON-RPC error is returned as *RPCError, a bad status without one as *HTTPError
+//and a response without result as *DecodeError, so the result of a nil error is never nil
+func (c *Client) post(url string, req *jsonrpcRequest) (*jsonrpcResponse, error) {
+	b, status, err := c.postRaw(url, req)
 	if err != nil {
-		return "", nil
+		return nil, err
 	}
-	if resp.Error != nil {
-		return "", errors.New(resp.Error.Message)
+	var res jsonrpcResponse
+	err = json.Unmarshal(b, &res)
+	if err == nil && res.Error != nil {
+		return nil, &RPCError{Code: res.Error.Code, Message: res.Error.Message, Data: res.Error.Data}
 	}
-	return resp.Result.Message, nil
+	if status < 200 || status > 299 {
+		return nil, &HTTPError{StatusCode: status, Body: truncate(b)}
+	}
+	if err != nil {
+		return nil, &DecodeError{Body: truncate(b), Err: err}
+	}
+	if res.Result == nil {
+		return nil, &DecodeError{Body: truncate(b), Err: errors.New("neither result nor error")}
+	}
+	return &res, nil
 }
 
-func post(url string, req *jsonrpcRequest) (*jsonrpcResponse, error) {
+//postRaw read the whole body and close it, so that the connection goes back to the keep-alive pool
+func (c *Client) postRaw(url string, req interface{}) ([]byte, int, error) {
 	msg, err := json.Marshal(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	body := strings.NewReader(string(msg))
-	resp, err := http.DefaultClient.Post(url, "application/json", body)
+	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(msg))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for k, v := range c.headers {
+		httpReq.Header.Set(k, v)
+	}
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, 0, &TransportError{Err: err}
+	}
+	defer resp.Body.Close()
 	b, err := ioutil.ReadAll(resp.Body)
-	var res jsonrpcResponse
-	err = json.Unmarshal(b, &res)
 	if err != nil {
-		return nil, err
+		return nil, resp.StatusCode, &TransportError{Err: err}
 	}
-	return &res, nil
+	return b, resp.StatusCode, nil
+}
+
+//truncate keep the start of a body in errors, the failures are grouped by message
+func truncate(b []byte) string {
+	const max = 200
+	s := strings.TrimSpace(string(b))
+	if len(s) > max {
+		return s[:max] + "..."
+	}
+	return s
 }
@@ -0,0 +1,128 @@
+package chaincode
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+	//defaultIdleConns is the keep-alive pool size, the one of http.DefaultTransport is only 2 per host
+	defaultIdleConns = 100
+)
+
+//ClientConfig tune the http client of a target
+type ClientConfig struct {
+	//Timeout cap a whole request including the read of the response, 30s by default
+	Timeout string `yaml:"timeout" json:"timeout"`
+	//MaxConns cap the connections to the peer, the keep-alive pool holds as many idle ones, 100 by default
+	MaxConns int `yaml:"max_conns" json:"max_conns"`
+	//KeepAlive reuse connections between requests, true by default
+	KeepAlive *bool `yaml:"keep_alive" json:"keep_alive"`
+	//HTTP2 talk HTTP/2, with TLS for https urls and in clear text (h2c) for http urls
+	HTTP2 bool `yaml:"http2" json:"http2"`
+	//Headers are added to every request, e.g. Authorization
+	Headers map[string]string `yaml:"headers" json:"headers"`
+}
+
+//MarshalJSON redact the header values, they may be credentials and the scenario is saved with the results
+func (cfg ClientConfig) MarshalJSON() ([]byte, error) {
+	type config ClientConfig
+	c := config(cfg)
+	if len(cfg.Headers) > 0 {
+		c.Headers = make(map[string]string, len(cfg.Headers))
+		for name := range cfg.Headers {
+			c.Headers[name] = "<redacted>"
+		}
+	}
+	return json.Marshal(c)
+}
+
+//Client send the JSON-RPC requests of the chaincode REST api
+type Client struct {
+	http    *http.Client
+	headers map[string]string
+}
+
+//DefaultClient is the client used by Query, Invoke and Raw
+var DefaultClient, _ = NewClient(ClientConfig{})
+
+//NewClient create a client with its own connection pool
+func NewClient(cfg ClientConfig) (*Client, error) {
+	timeout := defaultTimeout
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid http timeout:%v", err)
+		}
+		timeout = d
+	}
+	if cfg.MaxConns < 0 {
+		return nil, errors.New("http max_conns must not be negative")
+	}
+	idle := cfg.MaxConns
+	if idle == 0 {
+		idle = defaultIdleConns
+	}
+
+	if cfg.HTTP2 && cfg.KeepAlive != nil && !*cfg.KeepAlive {
+		return nil, errors.New("http2 needs keep_alive")
+	}
+
+	t := newTransport(cfg, idle)
+	var rt http.RoundTripper = t
+	if cfg.HTTP2 {
+		if err := http2.ConfigureTransport(t); err != nil {
+			return nil, err
+		}
+		//clear text http/2 for http urls, a single connection carries all the requests
+		h2c := &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		}
+		rt = &schemeTransport{http: h2c, https: t}
+	}
+
+	return &Client{
+		http:    &http.Client{Transport: rt, Timeout: timeout},
+		headers: cfg.Headers,
+	}, nil
+}
+
+func newTransport(cfg ClientConfig, idle int) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:        idle,
+		MaxIdleConnsPerHost: idle,
+		MaxConnsPerHost:     cfg.MaxConns,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		DisableKeepAlives:   cfg.KeepAlive != nil && !*cfg.KeepAlive,
+	}
+}
+
+//schemeTransport send http requests with h2c and https requests with the tls transport
+type schemeTransport struct {
+	http  http.RoundTripper
+	https http.RoundTripper
+}
+
+func (t *schemeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		return t.http.RoundTrip(req)
+	}
+	return t.https.RoundTrip(req)
+}
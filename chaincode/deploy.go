@@ -0,0 +1,75 @@
+package chaincode
+
+import (
+	"errors"
+
+	"golang.org/x/net/context"
+
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+//DeploySpec is the chaincode to deploy, by Path or by Name for a peer in dev mode
+type DeploySpec struct {
+	Path string
+	Name string
+	//Args are the constructor args, the function first
+	Args []string
+	//SecureContext is the enrollment id of the logged in user on a network with security
+	SecureContext string
+}
+
+//Deployer deploy chaincode to the peer at addr and return its chaincode id
+type Deployer interface {
+	Deploy(addr string, spec DeploySpec) (string, error)
+}
+
+//Deploy send the deploy JSON-RPC request, the chaincode id is the result message
+func (c *Client) Deploy(url string, spec DeploySpec) (string, error) {
+	req := &jsonrpcRequest{
+		JSONRPC: "2.0",
+		Method:  "deploy",
+		ID:      1,
+		Params: jsonrpcParam{
+			Type:          1,
+			ChaincodeID:   jsonrpcChaincode{Path: spec.Path, Name: spec.Name},
+			CtorMsg:       jsonrcpCtorMsg{Args: spec.Args},
+			SecureContext: spec.SecureContext,
+		},
+	}
+	resp, err := c.post(url, req)
+	if err != nil {
+		return "", err
+	}
+	if resp.Result.Message == "" {
+		return "", errors.New("deploy returned no chaincode id")
+	}
+	return resp.Result.Message, nil
+}
+
+//Deploy call the Deploy method of the Devops service, the chaincode id is in the returned deployment spec
+func (c *DevopsClient) Deploy(addr string, spec DeploySpec) (string, error) {
+	devops, err := c.devops(addr)
+	if err != nil {
+		return "", err
+	}
+	input := make([][]byte, len(spec.Args))
+	for i, a := range spec.Args {
+		input[i] = []byte(a)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	dep, err := devops.Deploy(ctx, &pb.ChaincodeSpec{
+		Type:          pb.ChaincodeSpec_GOLANG,
+		ChaincodeID:   &pb.ChaincodeID{Path: spec.Path, Name: spec.Name},
+		CtorMsg:       &pb.ChaincodeInput{Args: input},
+		SecureContext: spec.SecureContext,
+	})
+	if err != nil {
+		return "", grpcError(err)
+	}
+	id := dep.GetChaincodeSpec().GetChaincodeID()
+	if id == nil || id.Name == "" {
+		return "", errors.New("deploy returned no chaincode id")
+	}
+	return id.Name, nil
+}
@@ -0,0 +1,164 @@
+package chaincode
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+//DevopsConfig tune the grpc client of the Devops service of a peer
+type DevopsConfig struct {
+	//Timeout cap a whole call, 30s by default
+	Timeout string `yaml:"timeout" json:"timeout"`
+	//TLS connect with tls, RootCert is the pem file of the ca of the peer, the system pool is used when it is empty
+	TLS        bool   `yaml:"tls" json:"tls"`
+	RootCert   string `yaml:"root_cert" json:"root_cert"`
+	ServerName string `yaml:"server_name" json:"server_name"`
+}
+
+//DevopsClient send queries and invokes straight to the Devops grpc service of the peers, without the REST gateway.
+//Every peer address has one connection, dialed on first use.
+type DevopsClient struct {
+	timeout time.Duration
+	opts    []grpc.DialOption
+	lock    sync.Mutex
+	conns   map[string]*grpc.ClientConn
+}
+
+//NewDevopsClient create a client, no connection is made before the first call
+func NewDevopsClient(cfg DevopsConfig) (*DevopsClient, error) {
+	c := &DevopsClient{timeout: defaultTimeout, conns: make(map[string]*grpc.ClientConn)}
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid devops timeout:%v", err)
+		}
+		c.timeout = d
+	}
+	switch {
+	case cfg.TLS && cfg.RootCert != "":
+		creds, err := credentials.NewClientTLSFromFile(cfg.RootCert, cfg.ServerName)
+		if err != nil {
+			return nil, fmt.Errorf("fail to load devops root_cert:%v", err)
+		}
+		c.opts = append(c.opts, grpc.WithTransportCredentials(creds))
+	case cfg.TLS:
+		c.opts = append(c.opts, grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(nil, cfg.ServerName)))
+	case cfg.RootCert != "":
+		return nil, errors.New("devops root_cert needs tls")
+	default:
+		c.opts = append(c.opts, grpc.WithInsecure())
+	}
+	return c, nil
+}
+
+func (c *DevopsClient) devops(addr string) (pb.DevopsClient, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	conn, ok := c.conns[addr]
+	if !ok {
+		var err error
+		if conn, err = grpc.Dial(addr, c.opts...); err != nil {
+			return nil, &TransportError{Err: err}
+		}
+		c.conns[addr] = conn
+	}
+	return pb.NewDevopsClient(conn), nil
+}
+
+//QueryOrInvoke return the txid of an invoke or the result message of a query
+func (c *DevopsClient) QueryOrInvoke(addr string, ccid string, args []string, isInvoke bool, secureContext string) (string, error) {
+	if isInvoke {
+		return c.Invoke(addr, ccid, args, secureContext)
+	}
+	return c.Query(addr, ccid, args, secureContext)
+}
+
+//Query return the result message of the query
+func (c *DevopsClient) Query(addr string, ccid string, args []string, secureContext string) (string, error) {
+	return c.call(addr, ccid, args, false, secureContext)
+}
+
+//Invoke return the txid of the invoke
+func (c *DevopsClient) Invoke(addr string, ccid string, args []string, secureContext string) (string, error) {
+	return c.call(addr, ccid, args, true, secureContext)
+}
+
+func (c *DevopsClient) call(addr string, ccid string, args []string, isInvoke bool, secureContext string) (string, error) {
+	devops, err := c.devops(addr)
+	if err != nil {
+		return "", err
+	}
+	input := make([][]byte, len(args))
+	for i, a := range args {
+		input[i] = []byte(a)
+	}
+	spec := &pb.ChaincodeInvocationSpec{ChaincodeSpec: &pb.ChaincodeSpec{
+		Type:          pb.ChaincodeSpec_GOLANG,
+		ChaincodeID:   &pb.ChaincodeID{Name: ccid},
+		CtorMsg:       &pb.ChaincodeInput{Args: input},
+		SecureContext: secureContext,
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	var resp *pb.Response
+	if isInvoke {
+		resp, err = devops.Invoke(ctx, spec)
+	} else {
+		resp, err = devops.Query(ctx, spec)
+	}
+	if err != nil {
+		return "", grpcError(err)
+	}
+	if resp.Status != pb.Response_SUCCESS {
+		return "", &GRPCError{Code: int(codes.Unknown), Message: string(resp.Msg)}
+	}
+	return string(resp.Msg), nil
+}
+
+//grpcError tell the failures to reach the peer from the errors returned by the peer
+func grpcError(err error) error {
+	switch code := grpc.Code(err); code {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return &TransportError{Err: err}
+	default:
+		return &GRPCError{Code: int(code), Message: grpc.ErrorDesc(err)}
+	}
+}
+
+//Login log the user in with the Login method of the Devops service
+func (c *DevopsClient) Login(addr string, enrollID string, enrollSecret string) error {
+	devops, err := c.devops(addr)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	resp, err := devops.Login(ctx, &pb.Secret{EnrollId: enrollID, EnrollSecret: enrollSecret})
+	if err != nil {
+		return grpcError(err)
+	}
+	if resp.Status != pb.Response_SUCCESS {
+		return &GRPCError{Code: int(codes.Unknown), Message: string(resp.Msg)}
+	}
+	return nil
+}
+
+//Close close the connections to the peers
+func (c *DevopsClient) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for addr, conn := range c.conns {
+		conn.Close()
+		delete(c.conns, addr)
+	}
+}
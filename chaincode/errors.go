@@ -0,0 +1,59 @@
+package chaincode
+
+import (
+	"fmt"
+)
+
+//TransportError is returned when the request could not be sent or the response could not be read
+type TransportError struct {
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("transport error:%v", e.Err)
+}
+
+//HTTPError is returned when the peer answered with a non 2xx status and no JSON-RPC error
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("http status %d:%s", e.StatusCode, e.Body)
+}
+
+//DecodeError is returned when the response is not a JSON-RPC response with a result or an error
+type DecodeError struct {
+	Body string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("fail to decode response %q:%v", e.Body, e.Err)
+}
+
+//RPCError is the error member of a JSON-RPC response
+type RPCError struct {
+	Code    int
+	Message string
+	Data    string
+}
+
+func (e *RPCError) Error() string {
+	if e.Data != "" {
+		return fmt.Sprintf("%s:%s", e.Message, e.Data)
+	}
+	return e.Message
+}
+
+//GRPCError is returned when the Devops service of the peer answered with an error or a failure status,
+//Code is the grpc code
+type GRPCError struct {
+	Code    int
+	Message string
+}
+
+func (e *GRPCError) Error() string {
+	return fmt.Sprintf("grpc error %d:%s", e.Code, e.Message)
+}
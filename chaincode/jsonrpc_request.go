@@ -11,10 +11,13 @@ type jsonrpcParam struct {
 	Type        int              `json:"type"` // 1
 	ChaincodeID jsonrpcChaincode `json:"chaincodeID"`
 	CtorMsg     jsonrcpCtorMsg   `json:"ctorMsg"`
+	//SecureContext is the enrollment id of the logged in user on a network with security
+	SecureContext string `json:"secureContext,omitempty"`
 }
 
 type jsonrpcChaincode struct {
-	Name string `json:"name"`
+	Path string `json:"path,omitempty"` //deploy only
+	Name string `json:"name,omitempty"`
 }
 
 type jsonrcpCtorMsg struct {
@@ -22,7 +25,7 @@ type jsonrcpCtorMsg struct {
 }
 
 //newJSONRPCRequest return a new jsonrpc request
-func newJSONRPCRequest(isInvoke bool, ccid string, args []string) *jsonrpcRequest {
+func newJSONRPCRequest(isInvoke bool, ccid string, args []string, secureContext string) *jsonrpcRequest {
 	var method string
 	if isInvoke {
 		method = "invoke"
@@ -41,6 +44,7 @@ func newJSONRPCRequest(isInvoke bool, ccid string, args []string) *jsonrpcReques
 			CtorMsg: jsonrcpCtorMsg{
 				Args: args,
 			},
+			SecureContext: secureContext,
 		},
 	}
 	return req
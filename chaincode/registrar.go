@@ -0,0 +1,35 @@
+package chaincode
+
+import (
+	"encoding/json"
+)
+
+type loginRequest struct {
+	EnrollID     string `json:"enrollId"`
+	EnrollSecret string `json:"enrollSecret"`
+}
+
+type loginResponse struct {
+	OK    string `json:"OK"`
+	Error string `json:"Error"`
+}
+
+//Login log the user in through the /registrar endpoint of the REST api, e.g. http://localhost:7050/registrar
+func (c *Client) Login(url string, enrollID string, enrollSecret string) error {
+	b, status, err := c.postRaw(url, &loginRequest{EnrollID: enrollID, EnrollSecret: enrollSecret})
+	if err != nil {
+		return err
+	}
+
+	var res loginResponse
+	if err := json.Unmarshal(b, &res); err != nil {
+		if status < 200 || status > 299 {
+			return &HTTPError{StatusCode: status, Body: truncate(b)}
+		}
+		return &DecodeError{Body: truncate(b), Err: err}
+	}
+	if res.Error != "" || status < 200 || status > 299 {
+		return &HTTPError{StatusCode: status, Body: res.Error}
+	}
+	return nil
+}
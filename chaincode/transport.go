@@ -0,0 +1,19 @@
+package chaincode
+
+//Transport send queries and invokes to the peer at addr, the REST url of the peer or the address of its grpc service
+type Transport interface {
+	//QueryOrInvoke return the txid of an invoke or the result message of a query,
+	//secureContext is the enrollment id of a logged in user on a network with security, empty otherwise
+	QueryOrInvoke(addr string, ccid string, args []string, isInvoke bool, secureContext string) (string, error)
+}
+
+//Registrar log users in, their enrollment id is then the secureContext of their requests
+type Registrar interface {
+	Login(addr string, enrollID string, enrollSecret string) error
+}
+
+//transports by scenario name
+const (
+	TransportREST   = "rest"
+	TransportDevops = "devops"
+)
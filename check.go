@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/shimron/stressingtool/runner"
+	"github.com/shimron/stressingtool/slo"
+)
+
+//exitViolated is the exit code of a run that missed its slo or regressed from its baseline
+const exitViolated = 3
+
+//check evaluate the slo of the run and compare it to the baseline summary, if any.
+//A missing baseline file is reported and skipped, so the first run of a pipeline can create it.
+func check(sum *runner.Summary, thresholds []slo.Threshold, baseline *slo.Baseline, baselineFile string) (bool, error) {
+	ok := true
+	if len(thresholds) > 0 {
+		checks, err := slo.Evaluate(sum, thresholds)
+		if err != nil {
+			return false, err
+		}
+		ok = slo.Print("SLO", checks) && ok
+	}
+
+	if baselineFile == "" {
+		return ok, nil
+	}
+	base, err := slo.LoadSummary(baselineFile)
+	if os.IsNotExist(err) {
+		fmt.Printf("baseline %s does not exist, comparison skipped\n", baselineFile)
+		return ok, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if baseline == nil {
+		baseline = &slo.Baseline{}
+	}
+	diffs, err := baseline.Compare(base, sum)
+	if err != nil {
+		return false, err
+	}
+	fmt.Printf("compared to baseline %s\n", baselineFile)
+	ok = slo.PrintDiff(diffs) && ok
+	return ok, nil
+}
+
+//warnBaseline warn when the summary is saved over the baseline the run was compared to
+func warnBaseline(summaryFile string, baselineFile string) {
+	if baselineFile == "" {
+		return
+	}
+	s, err1 := filepath.Abs(summaryFile)
+	b, err2 := filepath.Abs(baselineFile)
+	if err1 == nil && err2 == nil && s == b {
+		fmt.Printf("warning:the summary replaces the baseline %s, the next run is compared to this one\n", baselineFile)
+	}
+}
@@ -0,0 +1,220 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//feeder modes
+const (
+	//ModeSequential read rows in file order
+	ModeSequential = "sequential"
+	//ModeRandom read rows at random
+	ModeRandom = "random"
+	//ModePerWorker give every worker of the runner its own row, the worker argument of Next is the row
+	ModePerWorker = "per_worker"
+)
+
+//what a sequential feeder does after its last row
+const (
+	//EndWrap start again from the first row
+	EndWrap = "wrap"
+	//EndStop stop generating jobs
+	EndStop = "stop"
+)
+
+//ErrExhausted is returned by a feeder with on_end "stop" after its last row
+var ErrExhausted = errors.New("feeder is exhausted")
+
+//FeederConfig bind template variables to the columns of a csv file or the fields of a jsonl file
+type FeederConfig struct {
+	//File is a .csv file with a header row or a .jsonl file with one json object per line
+	File string `yaml:"file" json:"file"`
+	//Mode is one of sequential, random and per_worker, sequential by default
+	Mode string `yaml:"mode" json:"mode"`
+	//OnEnd is wrap or stop, wrap by default
+	OnEnd string `yaml:"on_end" json:"on_end"`
+	//Vars map template variable names to column or field names, all columns are bound by their own name when it is empty
+	Vars map[string]string `yaml:"vars" json:"vars"`
+}
+
+//Feeder hand out the rows of a data file as template variables
+type Feeder struct {
+	FeederConfig
+	rows []map[string]string
+	next int
+	rnd  *rand.Rand
+}
+
+//LoadFeeder read all rows of the feeder file, a relative path is resolved against dir
+func LoadFeeder(cfg FeederConfig, dir string, seed int64) (*Feeder, error) {
+	if cfg.Mode == "" {
+		cfg.Mode = ModeSequential
+	}
+	if cfg.OnEnd == "" {
+		cfg.OnEnd = EndWrap
+	}
+	switch cfg.Mode {
+	case ModeSequential, ModeRandom, ModePerWorker:
+	default:
+		return nil, fmt.Errorf("unknown feeder mode %q", cfg.Mode)
+	}
+	switch cfg.OnEnd {
+	case EndWrap, EndStop:
+	default:
+		return nil, fmt.Errorf("unknown feeder on_end %q", cfg.OnEnd)
+	}
+
+	path := cfg.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rows []map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		rows, err = readCSV(f)
+	case ".jsonl":
+		rows, err = readJSONL(f)
+	default:
+		err = errors.New("feeder file must be .csv or .jsonl")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fail to read feeder %s:%v", cfg.File, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("feeder %s has no rows", cfg.File)
+	}
+	//every row must have the bound columns, a missing one would render as an empty string
+	vars := cfg.Vars
+	if len(vars) == 0 {
+		vars = make(map[string]string, len(rows[0]))
+		for col := range rows[0] {
+			vars[col] = col
+		}
+	}
+	for i, row := range rows {
+		for name, col := range vars {
+			if _, ok := row[col]; !ok {
+				return nil, fmt.Errorf("feeder %s row %d has no column %q for variable %q", cfg.File, i+1, col, name)
+			}
+		}
+	}
+
+	return &Feeder{
+		FeederConfig: cfg,
+		rows:         rows,
+		rnd:          rand.New(rand.NewSource(seed)),
+	}, nil
+}
+
+//Clone return a feeder sharing the rows but starting over from the beginning
+func (f *Feeder) Clone(seed int64) *Feeder {
+	return &Feeder{
+		FeederConfig: f.FeederConfig,
+		rows:         f.rows,
+		rnd:          rand.New(rand.NewSource(seed)),
+	}
+}
+
+//Len return the number of rows
+func (f *Feeder) Len() int {
+	return len(f.rows)
+}
+
+//Next return the variables of the next row for the given worker,
+//it is not safe for concurrent use except in per_worker mode which keeps no cursor
+func (f *Feeder) Next(worker int) (map[string]string, error) {
+	var i int
+	switch f.Mode {
+	case ModeRandom:
+		i = f.rnd.Intn(len(f.rows))
+	case ModePerWorker:
+		i = worker
+	default:
+		i = f.next
+		f.next++
+	}
+	if i >= len(f.rows) {
+		if f.OnEnd == EndStop {
+			return nil, ErrExhausted
+		}
+		i = i % len(f.rows)
+	}
+	return f.bind(f.rows[i]), nil
+}
+
+func (f *Feeder) bind(row map[string]string) map[string]string {
+	if len(f.Vars) == 0 {
+		return row
+	}
+	vars := make(map[string]string, len(f.Vars))
+	for name, col := range f.Vars {
+		vars[name] = row[col]
+	}
+	return vars
+}
+
+func readCSV(r io.Reader) ([]map[string]string, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, rec := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, col := range header {
+			row[col] = rec[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func readJSONL(r io.Reader) ([]map[string]string, error) {
+	var rows []map[string]string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		b := bytes.TrimSpace(sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(b, &obj); err != nil {
+			return nil, fmt.Errorf("line %d:%v", line, err)
+		}
+		row := make(map[string]string, len(obj))
+		for k, v := range obj {
+			//strings are unquoted, any other value is kept as raw json
+			var s string
+			if err := json.Unmarshal(v, &s); err == nil {
+				row[k] = s
+			} else {
+				row[k] = string(v)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, sc.Err()
+}
@@ -0,0 +1,19 @@
+package generator
+
+var firstNames = []string{
+	"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael", "Linda",
+	"William", "Elizabeth", "David", "Barbara", "Richard", "Susan", "Joseph", "Jessica",
+	"Thomas", "Sarah", "Charles", "Karen", "Wei", "Fang", "Lei", "Min",
+	"Jing", "Tao", "Yan", "Hui", "Jun", "Ling", "Hiroshi", "Yuki",
+}
+
+var lastNames = []string{
+	"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis",
+	"Rodriguez", "Martinez", "Hernandez", "Lopez", "Wilson", "Anderson", "Taylor", "Moore",
+	"Wang", "Li", "Zhang", "Liu", "Chen", "Yang", "Huang", "Zhao",
+	"Wu", "Zhou", "Xu", "Sun", "Ma", "Zhu", "Sato", "Suzuki",
+}
+
+var domains = []string{
+	"example.com", "example.org", "example.net", "test.com",
+}
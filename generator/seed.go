@@ -0,0 +1,17 @@
+package generator
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+//SubSeed derive the seed of one random source from the seed of the scenario and a tag naming its consumer,
+//so that the templates, feeders and pickers of a scenario do not draw the same sequence
+func SubSeed(seed int64, tag string) int64 {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(seed))
+	h := fnv.New64a()
+	h.Write(b[:])
+	h.Write([]byte(tag))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,147 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+//Data is the data passed to every args template
+type Data struct {
+	//Seq is the sequence number of the job
+	Seq int
+	//Vars hold the variables read by feeders, e.g. {{.Vars.email}}
+	Vars map[string]string
+}
+
+//Template render chaincode args from go templates.
+//All random values come from one source seeded with Seed,
+//so rendering the same templates in the same order with the same seed gives the same args.
+type Template struct {
+	Seed      int64
+	rnd       *rand.Rand
+	counters  map[string]int
+	templates []*template.Template
+}
+
+//NewTemplate compile the args templates, each element of args is one template
+func NewTemplate(seed int64, args []string) (*Template, error) {
+	t := &Template{
+		Seed:     seed,
+		rnd:      rand.New(rand.NewSource(seed)),
+		counters: make(map[string]int),
+	}
+	funcs := t.funcs()
+	for i, arg := range args {
+		tpl, err := template.New(fmt.Sprintf("args[%d]", i)).Option("missingkey=error").Funcs(funcs).Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid args template:%v", err)
+		}
+		t.templates = append(t.templates, tpl)
+	}
+	return t, nil
+}
+
+//Render render all templates with data, it is not safe for concurrent use
+func (t *Template) Render(data Data) ([]string, error) {
+	args := make([]string, 0, len(t.templates))
+	for _, tpl := range t.templates {
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("fail to render %s:%v", tpl.Name(), err)
+		}
+		args = append(args, buf.String())
+	}
+	return args, nil
+}
+
+func (t *Template) funcs() template.FuncMap {
+	return template.FuncMap{
+		"seq":        t.seq,
+		"randInt":    t.randInt,
+		"randString": t.randString,
+		"uuid":       t.uuid,
+		"pick":       t.pick,
+		"firstName":  t.firstName,
+		"lastName":   t.lastName,
+		"name":       t.name,
+		"email":      t.email,
+		"now":        now,
+		"unix":       func() int64 { return time.Now().Unix() },
+		"unixMilli":  func() int64 { return time.Now().UnixNano() / int64(time.Millisecond) },
+	}
+}
+
+//seq return the next value of the named counter, counters start at 1
+func (t *Template) seq(name string) int {
+	t.counters[name]++
+	return t.counters[name]
+}
+
+//randInt return a random int in [min, max]
+func (t *Template) randInt(min, max int) (int, error) {
+	if max < min {
+		return 0, fmt.Errorf("randInt: max %d is less than min %d", max, min)
+	}
+	return min + t.rnd.Intn(max-min+1), nil
+}
+
+//randString return a random alphanumeric string of length n
+func (t *Template) randString(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letters[t.rnd.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+//uuid return a random (version 4) uuid
+func (t *Template) uuid() string {
+	b := make([]byte, 16)
+	t.rnd.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+//pick return one of items at random
+func (t *Template) pick(items ...string) (string, error) {
+	if len(items) == 0 {
+		return "", fmt.Errorf("pick: no items")
+	}
+	return items[t.rnd.Intn(len(items))], nil
+}
+
+func (t *Template) firstName() string {
+	return firstNames[t.rnd.Intn(len(firstNames))]
+}
+
+func (t *Template) lastName() string {
+	return lastNames[t.rnd.Intn(len(lastNames))]
+}
+
+func (t *Template) name() string {
+	return t.firstName() + " " + t.lastName()
+}
+
+//email return a fake email address like "mary.smith4821@example.com"
+func (t *Template) email() string {
+	return fmt.Sprintf("%s.%s%d@%s",
+		strings.ToLower(t.firstName()),
+		strings.ToLower(t.lastName()),
+		t.rnd.Intn(10000),
+		domains[t.rnd.Intn(len(domains))])
+}
+
+//now return the current time formatted with the optional layout, RFC3339 by default
+func now(layout ...string) string {
+	if len(layout) > 0 {
+		return time.Now().Format(layout[0])
+	}
+	return time.Now().Format(time.RFC3339)
+}
@@ -0,0 +1,107 @@
+package generator
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTemplateRender(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		data Data
+		//want is the rendered args, or a regexp per arg when match is set
+		want  []string
+		match bool
+		err   bool
+	}{
+		{name: "plain", args: []string{"a", "b"}, want: []string{"a", "b"}},
+		{name: "seq", args: []string{"job{{.Seq}}"}, data: Data{Seq: 7}, want: []string{"job7"}},
+		{name: "vars", args: []string{`{"userEmail":"{{.Vars.email}}"}`}, data: Data{Vars: map[string]string{"email": "a@b.c"}}, want: []string{`{"userEmail":"a@b.c"}`}},
+		{name: "missing var", args: []string{"{{.Vars.email}}"}, data: Data{Vars: map[string]string{}}, err: true},
+		{name: "counters", args: []string{"{{seq `a`}}{{seq `a`}}{{seq `b`}}"}, want: []string{"121"}},
+		{name: "randInt single value", args: []string{"{{randInt 5 5}}"}, want: []string{"5"}},
+		{name: "randInt inverted", args: []string{"{{randInt 5 4}}"}, err: true},
+		{name: "pick one", args: []string{"{{pick `x`}}"}, want: []string{"x"}},
+		{name: "pick nothing", args: []string{"{{pick}}"}, err: true},
+		{name: "randString", args: []string{"{{randString 12}}"}, want: []string{"^[a-zA-Z0-9]{12}$"}, match: true},
+		{name: "uuid", args: []string{"{{uuid}}"}, want: []string{"^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$"}, match: true},
+		{name: "email", args: []string{"{{email}}"}, want: []string{`^[a-z]+\.[a-z]+[0-9]{1,4}@[a-z.]+$`}, match: true},
+		{name: "name", args: []string{"{{name}}"}, want: []string{"^[A-Z][a-z]+ [A-Z][a-z]+$"}, match: true},
+		{name: "now layout", args: []string{"{{now `2006`}}"}, want: []string{"^[0-9]{4}$"}, match: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, err := NewTemplate(1, tt.args)
+			if err != nil {
+				t.Fatalf("NewTemplate failed:%v", err)
+			}
+			got, err := tpl.Render(tt.data)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("Render = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render failed:%v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Render = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if tt.match && !regexp.MustCompile(tt.want[i]).MatchString(got[i]) {
+					t.Errorf("arg %d = %q, want a match of %s", i, got[i], tt.want[i])
+				}
+				if !tt.match && got[i] != tt.want[i] {
+					t.Errorf("arg %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTemplateParseError(t *testing.T) {
+	tests := []string{"{{.Seq", "{{unknown}}", "{{end}}"}
+	for _, arg := range tests {
+		if _, err := NewTemplate(1, []string{arg}); err == nil {
+			t.Errorf("NewTemplate(%q) did not fail", arg)
+		}
+	}
+}
+
+func TestTemplateSeed(t *testing.T) {
+	args := []string{"{{randInt 0 1000000}}", "{{uuid}}", "{{randString 8}}", "{{email}}"}
+	render := func(seed int64) string {
+		tpl, err := NewTemplate(seed, args)
+		if err != nil {
+			t.Fatalf("NewTemplate failed:%v", err)
+		}
+		var out []string
+		for seq := 1; seq <= 3; seq++ {
+			rendered, err := tpl.Render(Data{Seq: seq})
+			if err != nil {
+				t.Fatalf("Render failed:%v", err)
+			}
+			out = append(out, rendered...)
+		}
+		return strings.Join(out, "|")
+	}
+	tests := []struct {
+		name string
+		a, b int64
+		same bool
+	}{
+		{name: "same seed", a: 42, b: 42, same: true},
+		{name: "other seed", a: 42, b: 43},
+		{name: "sub seeds", a: SubSeed(42, "args"), b: SubSeed(42, "mix")},
+		{name: "sub seed is stable", a: SubSeed(42, "feeder/0"), b: SubSeed(42, "feeder/0"), same: true},
+	}
+	for _, tt := range tests {
+		a, b := render(tt.a), render(tt.b)
+		if (a == b) != tt.same {
+			t.Errorf("%s: seeds %d and %d rendered %q and %q", tt.name, tt.a, tt.b, a, b)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package generator
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+)
+
+//Weighted draw indexes at random in proportion to their weights
+type Weighted struct {
+	cumulative []float64
+	rnd        *rand.Rand
+}
+
+//NewWeighted create a picker over weights, all weights must be positive
+func NewWeighted(weights []float64, seed int64) (*Weighted, error) {
+	if len(weights) == 0 {
+		return nil, errors.New("no weights")
+	}
+	cumulative := make([]float64, len(weights))
+	var sum float64
+	for i, w := range weights {
+		if w <= 0 {
+			return nil, errors.New("weights must be positive")
+		}
+		sum += w
+		cumulative[i] = sum
+	}
+	return &Weighted{
+		cumulative: cumulative,
+		rnd:        rand.New(rand.NewSource(seed)),
+	}, nil
+}
+
+//Pick return the next index, it is not safe for concurrent use
+func (w *Weighted) Pick() int {
+	x := w.rnd.Float64() * w.cumulative[len(w.cumulative)-1]
+	return sort.Search(len(w.cumulative), func(i int) bool { return w.cumulative[i] > x })
+}
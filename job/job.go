@@ -12,13 +12,37 @@ type Job struct {
 	Name       string           `json:"name"`
 	SubmitTime time.Time        `json:"submit_time"`
 	Command    ChainCodeCommand `json:"command"`
+	//Operation is the name of the operation the job was drawn from, e.g. a mix entry or workflow step
+	Operation string `json:"operation"`
+	//Flow is set on the steps of a multi-step workflow
+	Flow Flow `json:"-"`
+	//WaitCommit makes the runner wait until the invoke is written to ledger before the next step
+	WaitCommit  bool          `json:"wait_commit"`
+	WaitTimeout time.Duration `json:"wait_timeout"`
+	//Tags group the job with others in the summary
+	Tags []string `json:"tags,omitempty"`
+	//Retry retry the failed calls of the job, nil never retries
+	Retry *RetryPolicy `json:"-"`
+	//Bind build the job for the worker that runs it, it is set when the job depends on the worker
+	Bind func(worker int) (*Job, error) `json:"-"`
+}
+
+//Flow chains the steps of a multi-step workflow run by one virtual user
+type Flow interface {
+	//Next return the job of the step following the finished one, or nil when the workflow is over
+	Next(js *JobStat) (*Job, error)
 }
 
 type ChainCodeCommand struct {
+	//URL is the REST url of the peer, or the address of its grpc service with the devops transport
 	URL      string   `json:"rest_url"`
 	CCID     string   `json:"chaincode_id"`
 	Args     []string `json:"args"`
 	IsInvoke bool     `json:"is_invoke"`
+	//SecureContext is the enrollment id the request is sent as on a network with security
+	SecureContext string `json:"secure_context,omitempty"`
+	//Transport send the request, chaincode.DefaultClient when it is nil
+	Transport chaincode.Transport `json:"-"`
 }
 
 func NewJob(name string, cmd ChainCodeCommand) *Job {
@@ -31,7 +55,33 @@ func NewJob(name string, cmd ChainCodeCommand) *Job {
 
 func (j *Job) Run() *JobStat {
 	j.SubmitTime = time.Now()
-	txid, err := chaincode.QueryOrInvoke(j.Command.URL, j.Command.CCID, j.Command.Args, j.Command.IsInvoke)
+	var transport chaincode.Transport = chaincode.DefaultClient
+	if j.Command.Transport != nil {
+		transport = j.Command.Transport
+	}
+	var result string
+	var err error
+	var retries int
+	for attempt := 1; ; attempt++ {
+		result, err = transport.QueryOrInvoke(j.Command.URL, j.Command.CCID, j.Command.Args, j.Command.IsInvoke, j.Command.SecureContext)
+		if err == nil {
+			break
+		}
+		class, _ := classify(err)
+		if !j.Retry.retry(class, attempt) {
+			break
+		}
+		retries++
+		time.Sleep(j.Retry.delay(attempt))
+	}
+	var txid string
+	if j.Command.IsInvoke {
+		txid = result
+	}
+	//only the next step of a workflow reads the result
+	if j.Flow == nil {
+		result = ""
+	}
 
 	var isSuccess = false
 	if err == nil && !j.Command.IsInvoke {
@@ -42,19 +92,35 @@ func (j *Job) Run() *JobStat {
 		isDone = false
 	}
 
-	var msg string
+	var msg, class string
+	var code int
 	if err != nil {
 		msg = err.Error()
+		class, code = classify(err)
+	}
+
+	var function string
+	if len(j.Command.Args) > 0 {
+		function = j.Command.Args[0]
 	}
 
 	return &JobStat{
 		JobID:        j.ID,
 		Name:         j.Name,
+		Operation:    j.Operation,
+		Function:     function,
+		ChaincodeID:  j.Command.CCID,
+		Tags:         j.Tags,
+		Identity:     j.Command.SecureContext,
 		TXID:         txid,
 		SubmitTime:   j.SubmitTime,
 		ExecutedTime: time.Now(),
 		IsDone:       isDone,
 		IsSuccess:    isSuccess,
 		ErrorMsg:     msg,
+		ErrorClass:   class,
+		ErrorCode:    code,
+		Retries:      retries,
+		Result:       result,
 	}
 }
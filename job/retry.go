@@ -0,0 +1,87 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//RetryClasses are the failure classes a job can retry, rejected and unconfirmed txs are only known after the job is done
+var RetryClasses = []string{ErrTransport, ErrHTTPStatus, ErrJSONRPC, ErrDecode, ErrGRPC}
+
+//RetryPolicy retry a failed call with an exponential backoff.
+//The execution cost of a retried job covers all its attempts and backoffs.
+type RetryPolicy struct {
+	//MaxAttempts count the first call, 1 never retries
+	MaxAttempts int
+	//Backoff is the delay before the first retry, it doubles after every retry up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	//Jitter shorten every delay by a random part of up to Jitter, from 0 to 1
+	Jitter float64
+	//Classes are the failure classes to retry
+	Classes []string
+
+	lock sync.Mutex
+	rand *rand.Rand
+}
+
+//NewRetryPolicy check the classes and create the policy
+func NewRetryPolicy(maxAttempts int, backoff time.Duration, maxBackoff time.Duration, jitter float64, classes []string) (*RetryPolicy, error) {
+	if maxAttempts < 1 {
+		return nil, errors.New("retry max_attempts must be at least 1")
+	}
+	if backoff < 0 || maxBackoff < backoff {
+		return nil, errors.New("retry needs 0 <= backoff <= max_backoff")
+	}
+	if jitter < 0 || jitter > 1 {
+		return nil, errors.New("retry jitter must be between 0 and 1")
+	}
+	for _, c := range classes {
+		known := false
+		for _, r := range RetryClasses {
+			known = known || c == r
+		}
+		if !known {
+			return nil, fmt.Errorf("class %q can not be retried, retryable classes are %v", c, RetryClasses)
+		}
+	}
+	return &RetryPolicy{
+		MaxAttempts: maxAttempts,
+		Backoff:     backoff,
+		MaxBackoff:  maxBackoff,
+		Jitter:      jitter,
+		Classes:     classes,
+		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+//retry tell if a call that failed with class should be attempted again after attempt attempts
+func (p *RetryPolicy) retry(class string, attempt int) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	for _, c := range p.Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+//delay return the backoff before the retry following attempt attempts
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	p.lock.Lock()
+	f := p.rand.Float64()
+	p.lock.Unlock()
+	return d - time.Duration(float64(d)*p.Jitter*f)
+}
@@ -2,12 +2,58 @@ package job
 
 import (
 	"time"
+
+	"github.com/shimron/stressingtool/chaincode"
+)
+
+//failure classes
+const (
+	//ErrTransport the request could not be sent or its response read
+	ErrTransport = "transport"
+	//ErrHTTPStatus the peer answered with a bad http status, ErrorCode is the status
+	ErrHTTPStatus = "http_status"
+	//ErrDecode the response is not a JSON-RPC response with a result or an error
+	ErrDecode = "decode"
+	//ErrJSONRPC the peer answered with a JSON-RPC error, ErrorCode is its code
+	ErrJSONRPC = "jsonrpc"
+	//ErrGRPC the devops service answered with an error, ErrorCode is the grpc code
+	ErrGRPC = "grpc"
+	//ErrRejected the tx received a rejection event
+	ErrRejected = "rejected"
+	//ErrUnconfirmed the tx received neither a block nor a rejection event
+	ErrUnconfirmed = "unconfirmed"
+	//ErrOther any other error
+	ErrOther = "other"
 )
 
+//classify return the class and code of an error returned by the chaincode package
+func classify(err error) (string, int) {
+	switch e := err.(type) {
+	case *chaincode.TransportError:
+		return ErrTransport, 0
+	case *chaincode.HTTPError:
+		return ErrHTTPStatus, e.StatusCode
+	case *chaincode.DecodeError:
+		return ErrDecode, 0
+	case *chaincode.RPCError:
+		return ErrJSONRPC, e.Code
+	case *chaincode.GRPCError:
+		return ErrGRPC, e.Code
+	default:
+		return ErrOther, 0
+	}
+}
+
 //JobStat ...
 type JobStat struct {
 	JobID           string    `json:"job_id"`
 	Name            string    `json:"name"`
+	Operation       string    `json:"operation"`
+	Function        string    `json:"function"`
+	ChaincodeID     string    `json:"chaincode_id"`
+	Tags            []string  `json:"tags,omitempty"`
+	Identity        string    `json:"identity,omitempty"`
+	Stage           string    `json:"stage,omitempty"`
 	TXID            string    `json:"txid"`
 	SubmitTime      time.Time `json:"submit_time"`
 	ExecutedTime    time.Time `json:"executed_time"`
@@ -15,4 +61,12 @@ type JobStat struct {
 	IsSuccess       bool      `json:"is_success"`
 	IsDone          bool      `json:"is_done"`
 	ErrorMsg        string    `json:"error_msg"`
+	//ErrorClass and ErrorCode classify the failure, see the Err* classes
+	ErrorClass string `json:"error_class,omitempty"`
+	ErrorCode  int    `json:"error_code,omitempty"`
+	//Retries is the number of calls retried by the retry policy, the outcome is the one of the last call
+	Retries int `json:"retries,omitempty"`
+	//Result is the txid of an invoke or the result message of a query of a workflow step,
+	//it is only kept in memory until the variables of the step were extracted
+	Result string `json:"-"`
 }
@@ -0,0 +1,217 @@
+package metrics
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+const (
+	//values are recorded in microseconds
+	unit = time.Microsecond
+	//subBucketBits set the precision: 2^8 sub buckets per power of two keep every value within 1%
+	subBucketBits  = 8
+	subBucketCount = 1 << subBucketBits
+	subBucketHalf  = subBucketCount / 2
+	//highest trackable value, larger values are counted in the last bucket
+	highest = int64(time.Hour / unit)
+)
+
+//DefaultBuckets are the upper bounds of the bucketed distribution of latencies
+var DefaultBuckets = []time.Duration{
+	1 * time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 20 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 200 * time.Millisecond, 500 * time.Millisecond,
+	1 * time.Second, 2 * time.Second, 5 * time.Second,
+	10 * time.Second, 30 * time.Second, 60 * time.Second,
+}
+
+//Histogram is a high dynamic range histogram of durations from 1µs to 1h with a precision of 1%.
+//Its memory is fixed, whatever the number of recorded values. It is safe for concurrent use.
+type Histogram struct {
+	lock   sync.Mutex
+	counts []int64
+	count  int64
+	sum    int64
+	min    int64
+	max    int64
+}
+
+//Bucket is one bucket of a distribution, Count values are <= UpperBound and > the previous bound
+type Bucket struct {
+	UpperBound time.Duration `json:"upper_bound"` //0 for +Inf
+	Count      int64         `json:"count"`
+}
+
+//NewHistogram create an empty histogram
+func NewHistogram() *Histogram {
+	return &Histogram{counts: make([]int64, bucketIndex(highest)+1)}
+}
+
+//bucketIndex return the index of the bucket of v: the first subBucketCount values map one to one,
+//above them every power of two is split in subBucketHalf buckets
+func bucketIndex(v int64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+	shift := uint(bits.Len64(uint64(v))) - subBucketBits
+	sub := v >> shift
+	return subBucketCount + int(shift-1)*subBucketHalf + int(sub-subBucketHalf)
+}
+
+//bucketValue return the highest value counted in the bucket at index i
+func bucketValue(i int) int64 {
+	if i < subBucketCount {
+		return int64(i)
+	}
+	shift := uint((i-subBucketCount)/subBucketHalf) + 1
+	sub := int64((i-subBucketCount)%subBucketHalf) + subBucketHalf
+	return (sub+1)<<shift - 1
+}
+
+//Record add one duration
+func (h *Histogram) Record(d time.Duration) {
+	v := int64(d / unit)
+	if v < 0 {
+		v = 0
+	}
+	i := bucketIndex(v)
+	if v > highest {
+		i = len(h.counts) - 1
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.counts[i]++
+	if h.count == 0 || v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	h.count++
+	h.sum += v
+}
+
+//Merge add all values of o
+func (h *Histogram) Merge(o *Histogram) {
+	o.lock.Lock()
+	counts := append([]int64(nil), o.counts...)
+	count, sum, min, max := o.count, o.sum, o.min, o.max
+	o.lock.Unlock()
+	if count == 0 {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for i, c := range counts {
+		h.counts[i] += c
+	}
+	if h.count == 0 || min < h.min {
+		h.min = min
+	}
+	if max > h.max {
+		h.max = max
+	}
+	h.count += count
+	h.sum += sum
+}
+
+//Reset remove all values
+func (h *Histogram) Reset() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for i := range h.counts {
+		h.counts[i] = 0
+	}
+	h.count, h.sum, h.min, h.max = 0, 0, 0, 0
+}
+
+//Count return the number of recorded values
+func (h *Histogram) Count() int64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.count
+}
+
+//Min return the exact lowest value
+func (h *Histogram) Min() time.Duration {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return time.Duration(h.min) * unit
+}
+
+//Max return the exact highest value
+func (h *Histogram) Max() time.Duration {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return time.Duration(h.max) * unit
+}
+
+//Mean return the exact average
+func (h *Histogram) Mean() time.Duration {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.count == 0 {
+		return 0
+	}
+	return time.Duration(float64(h.sum)/float64(h.count)) * unit
+}
+
+//Sum return the exact sum of the values
+func (h *Histogram) Sum() time.Duration {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return time.Duration(h.sum) * unit
+}
+
+//Percentile return the value below which p percent of the values are, e.g. Percentile(99.9)
+func (h *Histogram) Percentile(p float64) time.Duration {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.count == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(p / 100 * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			//never report more than the exact max
+			v := bucketValue(i)
+			if v > h.max || i == len(h.counts)-1 {
+				v = h.max
+			}
+			return time.Duration(v) * unit
+		}
+	}
+	return time.Duration(h.max) * unit
+}
+
+//Buckets return the distribution over the upper bounds, plus a last +Inf bucket.
+//Counts are per bucket, a value falls in a bucket when its histogram bucket is within the bound.
+func (h *Histogram) Buckets(bounds []time.Duration) []Bucket {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	buckets := make([]Bucket, len(bounds)+1)
+	for i, b := range bounds {
+		buckets[i].UpperBound = b
+	}
+	j := 0
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		v := time.Duration(bucketValue(i)) * unit
+		for j < len(bounds) && v > bounds[j] {
+			j++
+		}
+		buckets[j].Count += c
+	}
+	return buckets
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketIndex(t *testing.T) {
+	tests := []struct {
+		v     int64
+		index int
+		value int64
+	}{
+		{v: 0, index: 0, value: 0},
+		{v: 1, index: 1, value: 1},
+		{v: 255, index: 255, value: 255},
+		//above subBucketCount every bucket holds 2 values, then 4 after the next power of two
+		{v: 256, index: 256, value: 257},
+		{v: 257, index: 256, value: 257},
+		{v: 258, index: 257, value: 259},
+		{v: 511, index: 383, value: 511},
+		{v: 512, index: 384, value: 515},
+		{v: 515, index: 384, value: 515},
+		{v: 516, index: 385, value: 519},
+		{v: 1000, index: 506, value: 1003},
+	}
+	for _, tt := range tests {
+		if got := bucketIndex(tt.v); got != tt.index {
+			t.Errorf("bucketIndex(%d) = %d, want %d", tt.v, got, tt.index)
+		}
+		if got := bucketValue(tt.index); got != tt.value {
+			t.Errorf("bucketValue(%d) = %d, want %d", tt.index, got, tt.value)
+		}
+	}
+}
+
+func TestBucketPrecision(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to int64
+	}{
+		{name: "one to one", from: 0, to: subBucketCount},
+		{name: "first split", from: subBucketCount, to: 4 * subBucketCount},
+		{name: "around a second", from: 999000, to: 1001000},
+		{name: "highest", from: highest - 1000, to: highest + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for v := tt.from; v < tt.to; v++ {
+				i := bucketIndex(v)
+				upper := bucketValue(i)
+				if upper < v {
+					t.Fatalf("value %d is above the upper bound %d of its bucket %d", v, upper, i)
+				}
+				if i > 0 && bucketValue(i-1) >= v {
+					t.Fatalf("value %d is within the bucket %d below its own", v, i-1)
+				}
+				if float64(upper-v) > float64(v)/100 {
+					t.Fatalf("value %d is reported as %d, more than 1%% off", v, upper)
+				}
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	ms := func(n int) []time.Duration {
+		values := make([]time.Duration, 0, n)
+		for i := 1; i <= n; i++ {
+			values = append(values, time.Duration(i)*time.Millisecond)
+		}
+		return values
+	}
+	tests := []struct {
+		name   string
+		values []time.Duration
+		p      float64
+		want   time.Duration
+		//exact percentiles must match, the others may be up to 1% above
+		exact bool
+	}{
+		{name: "empty", values: nil, p: 99, want: 0, exact: true},
+		{name: "single value", values: []time.Duration{515 * time.Microsecond}, p: 50, want: 515 * time.Microsecond, exact: true},
+		{name: "capped at the max", values: []time.Duration{516 * time.Microsecond}, p: 99, want: 516 * time.Microsecond, exact: true},
+		{name: "one to one buckets", values: []time.Duration{10 * time.Microsecond, 20 * time.Microsecond, 30 * time.Microsecond}, p: 50, want: 20 * time.Microsecond, exact: true},
+		{name: "bucket upper bound", values: []time.Duration{514 * time.Microsecond, 515 * time.Microsecond, 600 * time.Microsecond}, p: 60, want: 515 * time.Microsecond, exact: true},
+		{name: "rank below one", values: ms(100), p: 0, want: time.Millisecond},
+		{name: "p50", values: ms(100), p: 50, want: 50 * time.Millisecond},
+		{name: "p99", values: ms(100), p: 99, want: 99 * time.Millisecond},
+		{name: "p100", values: ms(100), p: 100, want: 100 * time.Millisecond, exact: true},
+		{name: "above highest", values: []time.Duration{time.Millisecond, 2 * time.Hour}, p: 100, want: 2 * time.Hour, exact: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHistogram()
+			for _, v := range tt.values {
+				h.Record(v)
+			}
+			got := h.Percentile(tt.p)
+			if tt.exact {
+				if got != tt.want {
+					t.Errorf("Percentile(%v) = %v, want %v", tt.p, got, tt.want)
+				}
+				return
+			}
+			if got < tt.want || got > tt.want+tt.want/100 {
+				t.Errorf("Percentile(%v) = %v, want %v within 1%%", tt.p, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Labels are the labels of a prometheus sample
+type Labels map[string]string
+
+//labelEscaper escape a label value as the text exposition format expects
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		v := labelEscaper.Replace(l[name])
+		pairs = append(pairs, name+"=\""+v+"\"")
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+//with return a copy of the labels with one more label
+func (l Labels) with(name string, value string) Labels {
+	c := make(Labels, len(l)+1)
+	for k, v := range l {
+		c[k] = v
+	}
+	c[name] = value
+	return c
+}
+
+//WriteHeader write the HELP and TYPE lines of a metric in the prometheus text format
+func WriteHeader(w io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+//WriteSample write one sample of a counter or a gauge
+func WriteSample(w io.Writer, name string, labels Labels, value float64) {
+	fmt.Fprintf(w, "%s%s %g\n", name, labels, value)
+}
+
+//WriteHistogram write a histogram as cumulative buckets in seconds, with its sum and count
+func WriteHistogram(w io.Writer, name string, labels Labels, h *Histogram, bounds []time.Duration) {
+	var cumulative int64
+	for _, b := range h.Buckets(bounds) {
+		cumulative += b.Count
+		le := "+Inf"
+		if b.UpperBound > 0 {
+			le = fmt.Sprintf("%g", b.UpperBound.Seconds())
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, labels.with("le", le), cumulative)
+	}
+	fmt.Fprintf(w, "%s_sum%s %g\n", name, labels, h.Sum().Seconds())
+	//the count must match the +Inf bucket even when values are recorded meanwhile
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, cumulative)
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+//Rolling keep the values of the last window only, in slots of one histogram each
+type Rolling struct {
+	lock  sync.Mutex
+	slot  time.Duration
+	slots []*Histogram
+	ids   []int64 //number of the time slot each histogram holds
+}
+
+//NewRolling create a rolling histogram over window, values expire slot by slot
+func NewRolling(window time.Duration, slot time.Duration) *Rolling {
+	n := int(window / slot)
+	if n < 1 {
+		n = 1
+	}
+	r := &Rolling{slot: slot, slots: make([]*Histogram, n), ids: make([]int64, n)}
+	for i := range r.slots {
+		r.slots[i] = NewHistogram()
+	}
+	return r
+}
+
+//Record add a value at the current time
+func (r *Rolling) Record(d time.Duration) {
+	id := time.Now().UnixNano() / int64(r.slot)
+	i := int(id % int64(len(r.slots)))
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.ids[i] != id {
+		r.slots[i].Reset()
+		r.ids[i] = id
+	}
+	r.slots[i].Record(d)
+}
+
+//Snapshot merge the values of the window into a new histogram
+func (r *Rolling) Snapshot() *Histogram {
+	oldest := time.Now().UnixNano()/int64(r.slot) - int64(len(r.slots)) + 1
+	h := NewHistogram()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i, s := range r.slots {
+		if r.ids[i] >= oldest {
+			h.Merge(s)
+		}
+	}
+	return h
+}
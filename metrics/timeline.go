@@ -0,0 +1,202 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+//EventKind is what happened to a job at some point of a run
+type EventKind int
+
+//kinds of timeline events
+const (
+	//Submitted job was sent
+	Submitted EventKind = iota
+	//Executed job got its response, Latency is the execution cost
+	Executed
+	//Errored job got an error instead of a response
+	Errored
+	//Confirmed tx was written to ledger, Latency is the confirm cost
+	Confirmed
+	//Rejected tx received a rejection event
+	Rejected
+)
+
+//Event is one timeline event
+type Event struct {
+	At      time.Time
+	Kind    EventKind
+	Latency time.Duration
+	//Pending is set on an Executed invoke that now waits to be confirmed
+	Pending bool
+}
+
+//Percentiles summarize the latencies of one interval
+type Percentiles struct {
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+func snapshot(h *Histogram) Percentiles {
+	return Percentiles{
+		P50: h.Percentile(50),
+		P90: h.Percentile(90),
+		P99: h.Percentile(99),
+		Max: h.Max(),
+	}
+}
+
+//Point is one interval of a timeline
+type Point struct {
+	Start     time.Time `json:"start"`
+	Offset    float64   `json:"offset"` //seconds since the start of the run
+	Submitted int64     `json:"submitted"`
+	Executed  int64     `json:"executed"`
+	Errors    int64     `json:"errors"`
+	Confirmed int64     `json:"confirmed"`
+	Rejected  int64     `json:"rejected"`
+	//InFlight and Unconfirmed are sampled at the end of the interval
+	InFlight    int64       `json:"in_flight"`
+	Unconfirmed int64       `json:"unconfirmed"`
+	Execution   Percentiles `json:"execution"`
+	Confirm     Percentiles `json:"confirm"`
+}
+
+//Timeline count what happened in every interval of a run.
+//Only the histograms of the current interval are kept, so events must be added in time order.
+type Timeline struct {
+	Start    time.Time     `json:"start"`
+	Interval time.Duration `json:"interval"`
+	Points   []Point       `json:"points"`
+
+	inFlight    int64
+	unconfirmed int64
+	execution   *Histogram
+	confirm     *Histogram
+}
+
+//NewTimeline create an empty timeline
+func NewTimeline(start time.Time, interval time.Duration) *Timeline {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &Timeline{
+		Start:     start,
+		Interval:  interval,
+		execution: NewHistogram(),
+		confirm:   NewHistogram(),
+	}
+}
+
+//BuildTimeline sort the events and add them to a new timeline
+func BuildTimeline(start time.Time, interval time.Duration, events []Event) *Timeline {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+	t := NewTimeline(start, interval)
+	for _, e := range events {
+		t.Add(e)
+	}
+	t.Close()
+	return t
+}
+
+//Add account one event, events before the start of the timeline go to the first interval
+func (t *Timeline) Add(e Event) {
+	i := int(e.At.Sub(t.Start) / t.Interval)
+	if i < 0 {
+		i = 0
+	}
+	//close every interval before the one of the event
+	for len(t.Points) <= i {
+		t.Close()
+		t.Points = append(t.Points, Point{
+			Start:  t.Start.Add(time.Duration(len(t.Points)) * t.Interval),
+			Offset: (time.Duration(len(t.Points)) * t.Interval).Seconds(),
+		})
+	}
+	p := &t.Points[i]
+	//a late event of a closed interval is counted but its latency is lost
+	current := i == len(t.Points)-1
+
+	switch e.Kind {
+	case Submitted:
+		p.Submitted++
+		t.inFlight++
+	case Executed:
+		p.Executed++
+		t.inFlight--
+		if e.Pending {
+			t.unconfirmed++
+		}
+		if current {
+			t.execution.Record(e.Latency)
+		}
+	case Errored:
+		p.Errors++
+		t.inFlight--
+	case Confirmed:
+		p.Confirmed++
+		t.unconfirmed--
+		if current {
+			t.confirm.Record(e.Latency)
+		}
+	case Rejected:
+		p.Rejected++
+		t.unconfirmed--
+	}
+	p.InFlight = t.inFlight
+	p.Unconfirmed = t.unconfirmed
+}
+
+//Close snapshot the latencies of the current interval and reset them
+func (t *Timeline) Close() {
+	if len(t.Points) == 0 {
+		return
+	}
+	p := &t.Points[len(t.Points)-1]
+	p.InFlight = t.inFlight
+	p.Unconfirmed = t.unconfirmed
+	p.Execution = snapshot(t.execution)
+	p.Confirm = snapshot(t.confirm)
+	t.execution.Reset()
+	t.confirm.Reset()
+}
+
+//WriteJSON write the timeline as json
+func (t *Timeline) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+//WriteCSV write one row per interval, latencies in seconds
+func (t *Timeline) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"start", "offset", "submitted", "executed", "errors", "confirmed", "rejected", "in_flight", "unconfirmed",
+		"execution_p50", "execution_p90", "execution_p99", "execution_max",
+		"confirm_p50", "confirm_p90", "confirm_p99", "confirm_max",
+	})
+	for _, p := range t.Points {
+		cw.Write([]string{
+			p.Start.Format(time.RFC3339Nano),
+			fmt.Sprintf("%.3f", p.Offset),
+			fmt.Sprint(p.Submitted), fmt.Sprint(p.Executed), fmt.Sprint(p.Errors),
+			fmt.Sprint(p.Confirmed), fmt.Sprint(p.Rejected),
+			fmt.Sprint(p.InFlight), fmt.Sprint(p.Unconfirmed),
+			seconds(p.Execution.P50), seconds(p.Execution.P90), seconds(p.Execution.P99), seconds(p.Execution.Max),
+			seconds(p.Confirm.P50), seconds(p.Confirm.P90), seconds(p.Confirm.P99), seconds(p.Confirm.Max),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.6f", d.Seconds())
+}
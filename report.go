@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/shimron/stressingtool/report"
+	"github.com/shimron/stressingtool/runner"
+	"github.com/shimron/stressingtool/slo"
+)
+
+var reportCmd = &command{
+	name:    "report",
+	usage:   "<results>",
+	summary: "rebuild the summary of a run from its saved results",
+}
+
+func init() {
+	reportCmd.run = runReport
+}
+
+func runReport(args []string) int {
+	fs := newFlagSet(reportCmd)
+	timeline := fs.StringP("timeline", "t", "", "also save the per interval timeline to this .csv or .json file")
+	interval := fs.Duration("interval", time.Second, "interval of the timeline")
+	summary := fs.StringP("summary", "s", "", "also save the summary to this json file")
+	jobs := fs.StringP("jobs", "j", "", "also save the raw stat of every job to this .csv, .json or .jsonl file")
+	htmlReport := fs.String("html", "", "also save the html report to this file")
+	failures := fs.StringP("failures", "f", "", "also save every failed job to this .csv or .json file")
+	baseline := fs.StringP("baseline", "b", "", "compare the run to this saved summary (overrides the baseline file of the scenario)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	jr, err := runner.LoadResults(fs.Arg(0))
+	if err != nil {
+		fmt.Printf("fail to load results:%v\n", err)
+		return 1
+	}
+	sum := jr.CollectStates()
+
+	//the slo and the tolerances are the ones of the scenario the run was made with
+	var config struct {
+		SLO      []slo.Threshold `json:"slo"`
+		Baseline *slo.Baseline   `json:"baseline"`
+	}
+	if len(jr.Config) > 0 {
+		if err := json.Unmarshal(jr.Config, &config); err != nil {
+			fmt.Printf("fail to read the scenario of the run:%v\n", err)
+			return 1
+		}
+	}
+	if *baseline == "" && config.Baseline != nil {
+		*baseline = config.Baseline.File
+	}
+	//the run is compared before its summary is saved, the summary may replace the baseline
+	ok, checkErr := check(sum, config.SLO, config.Baseline, *baseline)
+
+	if *summary != "" {
+		warnBaseline(*summary, *baseline)
+		if err := sum.Save(*summary); err != nil {
+			fmt.Printf("fail to save summary:%v\n", err)
+			return 1
+		}
+		fmt.Printf("summary was saved to %s\n", *summary)
+	}
+	if *jobs != "" {
+		if err := jr.SaveJobs(*jobs); err != nil {
+			fmt.Printf("fail to save jobs:%v\n", err)
+			return 1
+		}
+		fmt.Printf("jobs were saved to %s\n", *jobs)
+	}
+
+	if *timeline != "" {
+		if err := jr.SaveTimeline(*timeline, *interval); err != nil {
+			fmt.Printf("fail to save timeline:%v\n", err)
+			return 1
+		}
+		fmt.Printf("timeline was saved to %s\n", *timeline)
+	}
+	if *failures != "" {
+		if err := jr.SaveFailures(*failures); err != nil {
+			fmt.Printf("fail to save failures:%v\n", err)
+			return 1
+		}
+		fmt.Printf("failures were saved to %s\n", *failures)
+	}
+	if *htmlReport != "" {
+		if err := saveHTML(*htmlReport, jr, sum, *interval); err != nil {
+			fmt.Printf("fail to save html report:%v\n", err)
+			return 1
+		}
+		fmt.Printf("html report was saved to %s\n", *htmlReport)
+	}
+
+	if checkErr != nil {
+		fmt.Printf("fail to check the run:%v\n", checkErr)
+		return 1
+	}
+	if !ok {
+		return exitViolated
+	}
+	return 0
+}
+
+//saveHTML write the html report of the run, built from its job stats, blocks and timeline
+func saveHTML(path string, jr *runner.JobRunner, sum *runner.Summary, interval time.Duration) error {
+	return report.Save(path, &report.Data{
+		Results:  jr.Results(),
+		Summary:  sum,
+		Timeline: jr.Timeline(interval),
+	})
+}
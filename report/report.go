@@ -0,0 +1,301 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/shimron/stressingtool/metrics"
+	"github.com/shimron/stressingtool/runner"
+)
+
+//maxDots cap the dots of a scatter series, the jobs are sampled evenly above it
+const maxDots = 4000
+
+//Data is what a report is built from: the recorded job stats and blocks of a run, its summary and its timeline
+type Data struct {
+	Results  *runner.Results
+	Summary  *runner.Summary
+	Timeline *metrics.Timeline
+}
+
+//breakdown is one table of groups, e.g. the operations
+type breakdown struct {
+	Title string
+	Rows  []breakdownRow
+}
+
+type breakdownRow struct {
+	Name string
+	*runner.Group
+}
+
+type view struct {
+	*Data
+	Generated   time.Time
+	Config      string
+	Percentiles []string
+	Breakdowns  []breakdown
+
+	Throughput          template.HTML
+	LatencyDistribution template.HTML
+	LatencyOverTime     template.HTML
+	BlockSizes          template.HTML
+	Errors              template.HTML
+}
+
+//Save write the report of a run to a self-contained html file
+func Save(path string, d *Data) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return Write(f, d)
+}
+
+//Write write the report of a run as a single html page, the charts are inline svg
+func Write(w io.Writer, d *Data) error {
+	v := &view{
+		Data:                d,
+		Generated:           time.Now(),
+		Percentiles:         []string{"p50", "p90", "p95", "p99", "p99.9"},
+		Throughput:          throughputChart(d.Timeline),
+		LatencyDistribution: latencyDistributionChart(d.Summary),
+		LatencyOverTime:     latencyOverTimeChart(d.Results),
+		BlockSizes:          blockSizesChart(d.Results),
+		Errors:              errorsChart(d.Summary),
+	}
+	if len(d.Results.Config) > 0 {
+		var b bytes.Buffer
+		if err := json.Indent(&b, d.Results.Config, "", "  "); err == nil {
+			v.Config = b.String()
+		}
+	}
+	s := d.Summary
+	for _, bd := range []struct {
+		title  string
+		groups map[string]*runner.Group
+	}{
+		{"Operations", s.Operations}, {"Functions", s.Functions}, {"Chaincodes", s.Chaincodes},
+		{"Tags", s.Tags}, {"Identities", s.Identities},
+	} {
+		if len(bd.groups) == 0 {
+			continue
+		}
+		rows := make([]breakdownRow, 0, len(bd.groups))
+		for name, g := range bd.groups {
+			if name == "" {
+				name = "(none)"
+			}
+			rows = append(rows, breakdownRow{Name: name, Group: g})
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i].Name < rows[j].Name })
+		v.Breakdowns = append(v.Breakdowns, breakdown{Title: bd.title, Rows: rows})
+	}
+	return page.Execute(w, v)
+}
+
+//throughputChart draw the jobs submitted, the txs confirmed and the failures of every interval, per second
+func throughputChart(tl *metrics.Timeline) template.HTML {
+	submitted := series{Name: "submitted/s", Color: colors[0]}
+	confirmed := series{Name: "confirmed/s", Color: colors[1]}
+	failed := series{Name: "errors+rejected/s", Color: colors[2]}
+	perSecond := tl.Interval.Seconds()
+	for _, p := range tl.Points {
+		submitted.Points = append(submitted.Points, point{p.Offset, float64(p.Submitted) / perSecond})
+		confirmed.Points = append(confirmed.Points, point{p.Offset, float64(p.Confirmed) / perSecond})
+		failed.Points = append(failed.Points, point{p.Offset, float64(p.Errors+p.Rejected) / perSecond})
+	}
+	return lineChart("Throughput", "seconds since start", "per second", []series{submitted, confirmed, failed})
+}
+
+//latencyDistributionChart draw the bucketed distribution of the execution and confirm costs
+func latencyDistributionChart(s *runner.Summary) template.HTML {
+	execution := series{Name: "execution", Color: colors[0]}
+	confirm := series{Name: "confirm", Color: colors[1]}
+	var labels []string
+	for i, b := range s.Total.Execution.Buckets {
+		label := "+Inf"
+		if b.UpperBound > 0 {
+			label = "<=" + time.Duration(math.Round(b.UpperBound*float64(time.Second))).String()
+		}
+		labels = append(labels, label)
+		execution.Values = append(execution.Values, float64(b.Count))
+		if i < len(s.Total.Confirm.Buckets) {
+			confirm.Values = append(confirm.Values, float64(s.Total.Confirm.Buckets[i].Count))
+		}
+	}
+	return barChart("Latency distribution", "jobs", labels, []series{execution, confirm})
+}
+
+//latencyOverTimeChart draw the execution cost of every job at its submit time
+//and the confirm cost of every tx at its confirm time
+func latencyOverTimeChart(res *runner.Results) template.HTML {
+	execution := series{Name: "execution", Color: colors[0]}
+	confirm := series{Name: "confirm", Color: colors[1]}
+	stride := int(math.Ceil(float64(len(res.JobStats)) / maxDots))
+	if stride < 1 {
+		stride = 1
+	}
+	for i := 0; i < len(res.JobStats); i += stride {
+		js := res.JobStats[i]
+		if js.TXID != "" || js.ErrorMsg == "" {
+			execution.Points = append(execution.Points,
+				point{js.SubmitTime.Sub(res.StartTime).Seconds(), js.ExecutedTime.Sub(js.SubmitTime).Seconds()})
+		}
+		if js.TXID != "" && js.IsSuccess && !js.TXConfirmedTime.IsZero() {
+			confirm.Points = append(confirm.Points,
+				point{js.TXConfirmedTime.Sub(res.StartTime).Seconds(), js.TXConfirmedTime.Sub(js.ExecutedTime).Seconds()})
+		}
+	}
+	title := "Latency over time"
+	if stride > 1 {
+		title = fmt.Sprintf("Latency over time (1 job in %d)", stride)
+	}
+	return scatterChart(title, "seconds since start", "seconds", []series{execution, confirm})
+}
+
+//blockSizesChart draw the txs of every block, ours and the others
+func blockSizesChart(res *runner.Results) template.HTML {
+	ours := series{Name: "our txs", Color: colors[0]}
+	others := series{Name: "other txs", Color: colors[7]}
+	for _, b := range res.Blocks {
+		x := b.CommitTime.Sub(res.StartTime).Seconds()
+		ours.Points = append(ours.Points, point{x, float64(b.OurTxCount)})
+		others.Points = append(others.Points, point{x, float64(b.TxCount - b.OurTxCount)})
+	}
+	return stackedBars("Block sizes", "seconds since start", "txs per block", []series{ours, others})
+}
+
+//errorsChart draw the failure count of every class
+func errorsChart(s *runner.Summary) template.HTML {
+	failures := series{Name: "failures", Color: colors[2]}
+	var labels []string
+	if s.Failures != nil {
+		for class := range s.Failures.Classes {
+			labels = append(labels, class)
+		}
+		sort.Strings(labels)
+		for _, class := range labels {
+			failures.Values = append(failures.Values, float64(s.Failures.Classes[class]))
+		}
+	}
+	return barChart("Failures by class", "jobs", labels, []series{failures})
+}
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"sec":    func(v float64) string { return fmt.Sprintf("%.4fs", v) },
+	"pct":    func(v float64) string { return fmt.Sprintf("%.2f%%", v) },
+	"num":    func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"mul100": func(v float64) float64 { return v * 100 },
+	"time": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04:05")
+	},
+}).Parse(pageTemplate))
+
+const pageTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Summary.Name}} report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 24px auto; max-width: 960px; color: #222; }
+h1 { font-size: 22px; } h2 { font-size: 17px; margin-top: 32px; border-bottom: 1px solid #ddd; padding-bottom: 4px; }
+table { border-collapse: collapse; font-size: 13px; margin: 8px 0; }
+th, td { border: 1px solid #ddd; padding: 3px 8px; text-align: right; }
+th { background: #f5f5f5; } td:first-child, th:first-child { text-align: left; }
+pre { background: #f5f5f5; padding: 8px; font-size: 12px; overflow-x: auto; }
+.chart { width: 100%; margin: 8px 0; }
+.chart .title { font-size: 14px; font-weight: bold; }
+.chart .tick { font-size: 11px; fill: #555; }
+.chart .label { font-size: 12px; fill: #333; }
+.chart .grid { stroke: #eee; } .chart .axis { stroke: #999; }
+</style>
+</head>
+<body>
+<h1>{{.Summary.Name}}</h1>
+<p>started {{time .Summary.StartTime}}, ran {{num .Summary.ExecutionTime}}s, ended after {{num .Summary.TotalTime}}s. generated {{time .Generated}}.</p>
+
+{{with .Summary.Total}}
+<h2>Summary</h2>
+<table>
+<tr><th>jobs</th><th>finished</th><th>successful</th><th>failed</th><th>success rate</th><th>retries</th><th>retried jobs</th></tr>
+<tr><td>{{.JobCount}}</td><td>{{.FinishedCount}}</td><td>{{.SuccessCount}}</td><td>{{.FailedCount}}</td><td>{{pct .SuccessRate}}</td><td>{{.RetryCount}}</td><td>{{.RetriedCount}}</td></tr>
+</table>
+<table>
+<tr><th>latency</th><th>min</th><th>avg</th>{{range $.Percentiles}}<th>{{.}}</th>{{end}}<th>max</th></tr>
+<tr><td>execution</td><td>{{sec .Execution.Min}}</td><td>{{sec .Execution.Mean}}</td>{{range $.Percentiles}}<td>{{sec (index $.Summary.Total.Execution.Percentiles .)}}</td>{{end}}<td>{{sec .Execution.Max}}</td></tr>
+<tr><td>confirm</td><td>{{sec .Confirm.Min}}</td><td>{{sec .Confirm.Mean}}</td>{{range $.Percentiles}}<td>{{sec (index $.Summary.Total.Confirm.Percentiles .)}}</td>{{end}}<td>{{sec .Confirm.Max}}</td></tr>
+</table>
+{{end}}
+{{with .Summary.OpenLoop}}
+<table>
+<tr><th>target rate</th><th>dropped</th><th>delayed</th><th>avg delay</th><th>max delay</th></tr>
+<tr><td>{{if gt .TargetRate 0.0}}{{num .TargetRate}}/s{{else}}stages{{end}}</td><td>{{.DroppedCount}}</td><td>{{.DelayedCount}}</td><td>{{sec .AvgDelay}}</td><td>{{sec .MaxDelay}}</td></tr>
+</table>
+{{end}}
+
+<h2>Charts</h2>
+{{.Throughput}}
+{{.LatencyDistribution}}
+{{.LatencyOverTime}}
+{{.BlockSizes}}
+{{.Errors}}
+
+{{range .Breakdowns}}
+<h2>{{.Title}}</h2>
+<table>
+<tr><th>name</th><th>jobs</th><th>successful</th><th>failed</th><th>success rate</th><th>retries</th><th>execution p50</th><th>execution p99</th><th>confirm p50</th><th>confirm p99</th></tr>
+{{range .Rows}}<tr><td>{{.Name}}</td><td>{{.JobCount}}</td><td>{{.SuccessCount}}</td><td>{{.FailedCount}}</td><td>{{pct .SuccessRate}}</td><td>{{.RetryCount}}</td><td>{{sec (index .Execution.Percentiles "p50")}}</td><td>{{sec (index .Execution.Percentiles "p99")}}</td><td>{{sec (index .Confirm.Percentiles "p50")}}</td><td>{{sec (index .Confirm.Percentiles "p99")}}</td></tr>
+{{end}}</table>
+{{end}}
+
+{{with .Summary.Stages}}
+<h2>Stages</h2>
+<table>
+<tr><th>stage</th><th>window</th><th>target</th><th>jobs</th><th>successful</th><th>failed</th><th>throughput</th><th>avg execution</th><th>avg confirm</th></tr>
+{{range .}}<tr><td>{{.Name}}</td><td>{{num .Start}}s-{{num .End}}s</td><td>{{num .From}}-&gt;{{num .To}}</td><td>{{.JobCount}}</td><td>{{.SuccessCount}}</td><td>{{.FailedCount}}</td><td>{{num .Throughput}}/s</td><td>{{sec .Execution.Mean}}</td><td>{{sec .Confirm.Mean}}</td></tr>
+{{end}}</table>
+{{end}}
+
+{{with .Summary.Saturation}}
+<h2>Saturation</h2>
+<table>
+<tr><th>target</th><th>throughput</th><th>avg latency</th><th>max latency</th><th>rejection rate</th></tr>
+{{range $i, $p := .Points}}<tr><td>{{num $p.Target}}{{if eq $i $.Summary.Saturation.Knee}} (knee){{end}}</td><td>{{num $p.Throughput}}/s</td><td>{{$p.AvgLatency}}</td><td>{{$p.MaxLatency}}</td><td>{{pct (mul100 $p.RejectionRate)}}</td></tr>
+{{end}}</table>
+<p>stopped because: {{.Reason}}</p>
+{{end}}
+
+{{with .Summary.Failures}}
+<h2>Failures</h2>
+<table>
+<tr><th>class</th><th>code</th><th>message</th><th>count</th><th>sample job ids</th><th>sample txids</th></tr>
+{{range .Groups}}<tr><td>{{.Class}}</td><td>{{if .Code}}{{.Code}}{{end}}</td><td>{{.Message}}</td><td>{{.Count}}</td><td>{{range .SampleJobIDs}}{{.}} {{end}}</td><td>{{range .SampleTXIDs}}{{.}} {{end}}</td></tr>
+{{end}}</table>
+{{if gt .Distinct (len .Groups)}}<p>{{len .Groups}} most frequent of {{.Distinct}} distinct failures.</p>{{end}}
+{{end}}
+
+{{with .Summary.Blocks}}
+<h2>Blocks</h2>
+<table>
+<tr><th>blocks</th><th>blocks/s</th><th>txs</th><th>our txs</th><th>txs per block p50</th><th>p90</th><th>max</th><th>avg share of our txs</th><th>blocks without our txs</th></tr>
+<tr><td>{{.BlockCount}}</td><td>{{num .BlocksPerSecond}}</td><td>{{.TxCount}}</td><td>{{.OurTxCount}}</td><td>{{.TxPerBlock.P50}}</td><td>{{.TxPerBlock.P90}}</td><td>{{.TxPerBlock.Max}}</td><td>{{pct .AvgOurShare}}</td><td>{{.ZeroOurBlocks}}</td></tr>
+</table>
+{{with .CommitInterval}}<p>commit interval: avg {{sec .Mean}}, p50 {{sec (index .Percentiles "p50")}}, p99 {{sec (index .Percentiles "p99")}}, max {{sec .Max}}</p>{{end}}
+{{end}}
+
+{{with .Config}}
+<h2>Scenario</h2>
+<pre>{{.}}</pre>
+{{end}}
+</body>
+</html>
+`
@@ -0,0 +1,237 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"strconv"
+)
+
+//size of every chart, the plot area is inside the margins
+const (
+	chartWidth   = 900
+	chartHeight  = 320
+	marginLeft   = 70
+	marginRight  = 20
+	marginTop    = 30
+	marginBottom = 45
+)
+
+//palette of the series, in order
+var colors = []string{"#1f77b4", "#2ca02c", "#d62728", "#ff7f0e", "#9467bd", "#8c564b", "#e377c2", "#7f7f7f"}
+
+type point struct {
+	X, Y float64
+}
+
+//series is one line, one set of dots or one set of bars of a chart
+type series struct {
+	Name   string
+	Color  string
+	Points []point
+	//Values are the bar heights of a bar chart, one per label
+	Values []float64
+}
+
+//plot draws a chart with linear axes
+type plot struct {
+	b          bytes.Buffer
+	xmin, xmax float64
+	ymin, ymax float64
+}
+
+func newPlot(title string, xLabel string, yLabel string, xmax float64, ymax float64) *plot {
+	p := &plot{xmax: niceCeil(xmax), ymax: niceCeil(ymax)}
+	fmt.Fprintf(&p.b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" class="chart">`, chartWidth, chartHeight)
+	fmt.Fprintf(&p.b, `<text x="%d" y="18" class="title">%s</text>`, marginLeft, html.EscapeString(title))
+
+	for _, v := range ticks(p.ymin, p.ymax) {
+		y := p.y(v)
+		fmt.Fprintf(&p.b, `<line x1="%d" x2="%d" y1="%.1f" y2="%.1f" class="grid"/>`, marginLeft, chartWidth-marginRight, y, y)
+		fmt.Fprintf(&p.b, `<text x="%d" y="%.1f" class="tick" text-anchor="end">%s</text>`, marginLeft-6, y+4, formatTick(v))
+	}
+	if xLabel != "" {
+		for _, v := range ticks(p.xmin, p.xmax) {
+			x := p.x(v)
+			fmt.Fprintf(&p.b, `<line x1="%.1f" x2="%.1f" y1="%d" y2="%d" class="axis"/>`, x, x, chartHeight-marginBottom, chartHeight-marginBottom+4)
+			fmt.Fprintf(&p.b, `<text x="%.1f" y="%d" class="tick" text-anchor="middle">%s</text>`, x, chartHeight-marginBottom+17, formatTick(v))
+		}
+		fmt.Fprintf(&p.b, `<text x="%d" y="%d" class="label" text-anchor="middle">%s</text>`,
+			(chartWidth+marginLeft)/2, chartHeight-6, html.EscapeString(xLabel))
+	}
+	fmt.Fprintf(&p.b, `<text transform="translate(14 %d) rotate(-90)" class="label" text-anchor="middle">%s</text>`,
+		(chartHeight+marginTop-marginBottom)/2, html.EscapeString(yLabel))
+	fmt.Fprintf(&p.b, `<line x1="%d" x2="%d" y1="%d" y2="%d" class="axis"/>`,
+		marginLeft, chartWidth-marginRight, chartHeight-marginBottom, chartHeight-marginBottom)
+	return p
+}
+
+func (p *plot) x(v float64) float64 {
+	return marginLeft + (v-p.xmin)/(p.xmax-p.xmin)*(chartWidth-marginLeft-marginRight)
+}
+
+func (p *plot) y(v float64) float64 {
+	return chartHeight - marginBottom - (v-p.ymin)/(p.ymax-p.ymin)*(chartHeight-marginTop-marginBottom)
+}
+
+func (p *plot) line(s series) {
+	if len(s.Points) == 0 {
+		return
+	}
+	fmt.Fprintf(&p.b, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="`, s.Color)
+	for _, pt := range s.Points {
+		fmt.Fprintf(&p.b, "%.1f,%.1f ", p.x(pt.X), p.y(pt.Y))
+	}
+	p.b.WriteString(`"/>`)
+}
+
+func (p *plot) dots(s series) {
+	fmt.Fprintf(&p.b, `<g fill="%s" fill-opacity="0.35">`, s.Color)
+	for _, pt := range s.Points {
+		fmt.Fprintf(&p.b, `<circle cx="%.1f" cy="%.1f" r="1.6"/>`, p.x(pt.X), p.y(pt.Y))
+	}
+	p.b.WriteString(`</g>`)
+}
+
+func (p *plot) rect(x float64, y float64, w float64, h float64, color string) {
+	fmt.Fprintf(&p.b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`, x, y, math.Max(w, 0.5), h, color)
+}
+
+func (p *plot) legend(all []series) {
+	x := float64(chartWidth - marginRight)
+	for i := len(all) - 1; i >= 0; i-- {
+		s := all[i]
+		x -= float64(len(s.Name))*7 + 24
+		fmt.Fprintf(&p.b, `<rect x="%.1f" y="9" width="10" height="10" fill="%s"/>`, x, s.Color)
+		fmt.Fprintf(&p.b, `<text x="%.1f" y="18" class="tick">%s</text>`, x+14, html.EscapeString(s.Name))
+	}
+}
+
+func (p *plot) html() template.HTML {
+	p.b.WriteString(`</svg>`)
+	return template.HTML(p.b.String())
+}
+
+//lineChart draw every series as a line
+func lineChart(title string, xLabel string, yLabel string, all []series) template.HTML {
+	xmax, ymax := bounds(all)
+	p := newPlot(title, xLabel, yLabel, xmax, ymax)
+	for _, s := range all {
+		p.line(s)
+	}
+	p.legend(all)
+	return p.html()
+}
+
+//scatterChart draw every point of every series as a dot
+func scatterChart(title string, xLabel string, yLabel string, all []series) template.HTML {
+	xmax, ymax := bounds(all)
+	p := newPlot(title, xLabel, yLabel, xmax, ymax)
+	for _, s := range all {
+		p.dots(s)
+	}
+	p.legend(all)
+	return p.html()
+}
+
+//stackedBars draw one bar per x value, the series are stacked on each other
+func stackedBars(title string, xLabel string, yLabel string, all []series) template.HTML {
+	var xmax, ymax float64
+	totals := make(map[float64]float64)
+	for _, s := range all {
+		for _, pt := range s.Points {
+			totals[pt.X] += pt.Y
+			xmax = math.Max(xmax, pt.X)
+			ymax = math.Max(ymax, totals[pt.X])
+		}
+	}
+	p := newPlot(title, xLabel, yLabel, xmax, ymax)
+	width := float64(chartWidth-marginLeft-marginRight) / math.Max(float64(len(totals)), 1) * 0.8
+	base := make(map[float64]float64)
+	for _, s := range all {
+		for _, pt := range s.Points {
+			top := p.y(base[pt.X] + pt.Y)
+			p.rect(p.x(pt.X)-width/2, top, width, p.y(base[pt.X])-top, s.Color)
+			base[pt.X] += pt.Y
+		}
+	}
+	p.legend(all)
+	return p.html()
+}
+
+//barChart draw the values of every series as grouped bars, one group per label
+func barChart(title string, yLabel string, labels []string, all []series) template.HTML {
+	var ymax float64
+	for _, s := range all {
+		for _, v := range s.Values {
+			ymax = math.Max(ymax, v)
+		}
+	}
+	p := newPlot(title, "", yLabel, 1, ymax)
+	group := float64(chartWidth-marginLeft-marginRight) / math.Max(float64(len(labels)), 1)
+	width := group * 0.8 / math.Max(float64(len(all)), 1)
+	for i, label := range labels {
+		x := marginLeft + float64(i)*group
+		for j, s := range all {
+			if i < len(s.Values) && s.Values[i] > 0 {
+				top := p.y(s.Values[i])
+				p.rect(x+group*0.1+float64(j)*width, top, width, p.y(0)-top, s.Color)
+			}
+		}
+		fmt.Fprintf(&p.b, `<text x="%.1f" y="%d" class="tick" text-anchor="middle">%s</text>`,
+			x+group/2, chartHeight-marginBottom+17, html.EscapeString(label))
+	}
+	p.legend(all)
+	return p.html()
+}
+
+//bounds return the largest x and y of the series
+func bounds(all []series) (float64, float64) {
+	var xmax, ymax float64
+	for _, s := range all {
+		for _, pt := range s.Points {
+			xmax = math.Max(xmax, pt.X)
+			ymax = math.Max(ymax, pt.Y)
+		}
+	}
+	return xmax, ymax
+}
+
+//niceStep round a raw tick step to 1, 2 or 5 times a power of ten
+func niceStep(raw float64) float64 {
+	exp := math.Pow(10, math.Floor(math.Log10(raw)))
+	switch f := raw / exp; {
+	case f <= 1:
+		return exp
+	case f <= 2:
+		return 2 * exp
+	case f <= 5:
+		return 5 * exp
+	default:
+		return 10 * exp
+	}
+}
+
+//niceCeil round the top of an axis up to a multiple of its tick step
+func niceCeil(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	step := niceStep(v / 5)
+	return math.Ceil(v/step) * step
+}
+
+func ticks(min float64, max float64) []float64 {
+	step := niceStep((max - min) / 5)
+	var values []float64
+	for v := min; v <= max+step/1000; v += step {
+		values = append(values, v)
+	}
+	return values
+}
+
+func formatTick(v float64) string {
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/shimron/stressingtool/scenario"
+)
+
+var runCmd = &command{
+	name:    "run",
+	usage:   "[flags] <scenario>",
+	summary: "start a load test described by a scenario file",
+}
+
+func init() {
+	runCmd.run = runRun
+}
+
+func runRun(args []string) int {
+	fs := newFlagSet(runCmd)
+	out := fs.StringP("out", "o", "", "file the results are saved to (default <name>_results.json)")
+	duration := fs.StringP("duration", "d", "", "stop the run after this duration, e.g. 30m (overrides the scenario)")
+	timeline := fs.StringP("timeline", "t", "", "file the per interval timeline is saved to, .csv or .json (default <name>_timeline.csv)")
+	interval := fs.Duration("interval", time.Second, "interval of the timeline")
+	metricsAddr := fs.StringP("metrics", "m", "", "serve live prometheus metrics on this address, e.g. :9100 (overrides the scenario)")
+	verbose := fs.BoolP("verbose", "v", false, "print a line for every job, block and rejection")
+	dashboard := fs.Bool("dashboard", isTerminal(), "redraw a live view of the run every second, on by default in a terminal")
+	summary := fs.StringP("summary", "s", "", "file the summary is saved to as json (default <name>_summary.json)")
+	jobs := fs.StringP("jobs", "j", "", "also save the raw stat of every job to this .csv, .json or .jsonl file")
+	htmlReport := fs.String("html", "", "file the html report is saved to (default <name>_report.html)")
+	failures := fs.StringP("failures", "f", "", "file every failed job is saved to, .csv or .json (default <name>_failures.csv)")
+	baseline := fs.StringP("baseline", "b", "", "compare the run to this saved summary (overrides the scenario baseline file)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	sc, err := scenario.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Printf("fail to load scenario:%v\n", err)
+		return 1
+	}
+	defer sc.Close()
+	if *duration != "" {
+		if err := sc.SetDuration(*duration); err != nil {
+			fmt.Printf("invalid duration:%v\n", err)
+			return 1
+		}
+	}
+	drain, _ := sc.DrainWait()
+	if err := sc.Login(); err != nil {
+		fmt.Printf("fail to log in:%v\n", err)
+		return 1
+	}
+	if sc.Deploy != nil {
+		if err := sc.DeployChaincode(); err != nil {
+			fmt.Printf("fail to set up the chaincode:%v\n", err)
+			return 1
+		}
+	}
+
+	fmt.Printf("running scenario %s with seed %d\n", sc.Name, sc.Seed)
+	if *metricsAddr != "" {
+		sc.MetricsAddr = *metricsAddr
+	}
+	jr := sc.NewRunner()
+	jr.Verbose = *verbose
+	stopDashboard := func() {}
+	if *dashboard {
+		//the per job lines would scroll the view away
+		stopDashboard = jr.StartDashboard(time.Second, isTerminal() && !*verbose)
+	}
+	jr.Execute(sc.Jobs(jr.StopChan))
+	jr.Drain(drain)
+	stopDashboard()
+	sum := jr.CollectStates()
+
+	//the run is compared before its summary is saved, the summary may replace the baseline
+	if *baseline == "" {
+		*baseline = sc.BaselineFile()
+	}
+	ok, checkErr := check(sum, sc.SLO, sc.Baseline, *baseline)
+
+	if *out == "" {
+		*out = sc.Name + "_results.json"
+	}
+	if err := jr.SaveResults(*out); err != nil {
+		fmt.Printf("fail to save results:%v\n", err)
+		return 1
+	}
+	fmt.Printf("results were saved to %s\n", *out)
+
+	if *summary == "" {
+		*summary = sc.Name + "_summary.json"
+	}
+	warnBaseline(*summary, *baseline)
+	if err := sum.Save(*summary); err != nil {
+		fmt.Printf("fail to save summary:%v\n", err)
+		return 1
+	}
+	fmt.Printf("summary was saved to %s\n", *summary)
+	if *jobs != "" {
+		if err := jr.SaveJobs(*jobs); err != nil {
+			fmt.Printf("fail to save jobs:%v\n", err)
+			return 1
+		}
+		fmt.Printf("jobs were saved to %s\n", *jobs)
+	}
+
+	if *timeline == "" {
+		*timeline = sc.Name + "_timeline.csv"
+	}
+	if err := jr.SaveTimeline(*timeline, *interval); err != nil {
+		fmt.Printf("fail to save timeline:%v\n", err)
+		return 1
+	}
+	fmt.Printf("timeline was saved to %s\n", *timeline)
+
+	if *failures == "" {
+		*failures = sc.Name + "_failures.csv"
+	}
+	if err := jr.SaveFailures(*failures); err != nil {
+		fmt.Printf("fail to save failures:%v\n", err)
+		return 1
+	}
+	fmt.Printf("failures were saved to %s\n", *failures)
+
+	if *htmlReport == "" {
+		*htmlReport = sc.Name + "_report.html"
+	}
+	if err := saveHTML(*htmlReport, jr, sum, *interval); err != nil {
+		fmt.Printf("fail to save html report:%v\n", err)
+		return 1
+	}
+	fmt.Printf("html report was saved to %s\n", *htmlReport)
+
+	if checkErr != nil {
+		fmt.Printf("fail to check the run:%v\n", checkErr)
+		return 1
+	}
+	if !ok {
+		return exitViolated
+	}
+	return 0
+}
+
+//isTerminal tell if the standard output is a terminal
+func isTerminal() bool {
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
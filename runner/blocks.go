@@ -0,0 +1,166 @@
+package runner
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/shimron/stressingtool/metrics"
+)
+
+//Block is what the block listener saw of one block
+type Block struct {
+	//CommitTime is the LocalLedgerCommitTimestamp of the block
+	CommitTime   time.Time `json:"commit_time"`
+	ReceivedTime time.Time `json:"received_time"`
+	TxCount      int       `json:"tx_count"`
+	//OurTxCount is the number of txs submitted by this run
+	OurTxCount int `json:"our_tx_count"`
+}
+
+//BlockSummary is the block rate, the tx per block distribution, the commit intervals and the share of our txs
+type BlockSummary struct {
+	BlockCount      int     `json:"block_count"`
+	BlocksPerSecond float64 `json:"blocks_per_second"`
+	TxCount         int     `json:"tx_count"`
+	OurTxCount      int     `json:"our_tx_count"`
+	TxPerBlock      struct {
+		Min  int     `json:"min"`
+		Mean float64 `json:"mean"`
+		P50  int     `json:"p50"`
+		P90  int     `json:"p90"`
+		P99  int     `json:"p99"`
+		Max  int     `json:"max"`
+	} `json:"tx_per_block"`
+	//TxPerBlockBuckets is the distribution of the tx count over txCountBuckets
+	TxPerBlockBuckets []CountBucket `json:"tx_per_block_buckets"`
+	//CommitInterval is the interval between the commit timestamps of consecutive blocks
+	CommitInterval *Latency `json:"commit_interval,omitempty"`
+	AvgOurShare    float64  `json:"avg_our_share"` //percent
+	ZeroOurBlocks  int      `json:"zero_our_blocks"`
+}
+
+//CountBucket is one bucket of a distribution of counts
+type CountBucket struct {
+	UpperBound int `json:"upper_bound"` //-1 for +Inf
+	Count      int `json:"count"`
+}
+
+//upper bounds of the tx per block distribution
+var txCountBuckets = []int{0, 1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+
+func (jr *JobRunner) addBlock(b Block) {
+	jr.blockLock.Lock()
+	jr.Blocks = append(jr.Blocks, b)
+	jr.blockLock.Unlock()
+}
+
+//blockSummary summarize the received blocks, nil when there is none
+func (jr *JobRunner) blockSummary() *BlockSummary {
+	jr.blockLock.Lock()
+	blocks := make([]Block, len(jr.Blocks))
+	copy(blocks, jr.Blocks)
+	jr.blockLock.Unlock()
+	if len(blocks) == 0 {
+		return nil
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].CommitTime.Before(blocks[j].CommitTime) })
+
+	s := &BlockSummary{BlockCount: len(blocks)}
+	var shareTotal float64
+	counts := make([]int, 0, len(blocks))
+	intervals := metrics.NewHistogram()
+	for i, b := range blocks {
+		s.TxCount += b.TxCount
+		s.OurTxCount += b.OurTxCount
+		counts = append(counts, b.TxCount)
+		if b.OurTxCount == 0 {
+			s.ZeroOurBlocks++
+		}
+		if b.TxCount > 0 {
+			shareTotal += float64(b.OurTxCount) / float64(b.TxCount)
+		}
+		if i > 0 {
+			intervals.Record(b.CommitTime.Sub(blocks[i-1].CommitTime))
+		}
+	}
+	sort.Ints(counts)
+
+	if span := blocks[len(blocks)-1].CommitTime.Sub(blocks[0].CommitTime); span > 0 {
+		s.BlocksPerSecond = float64(len(blocks)-1) / span.Seconds()
+	}
+	s.TxPerBlock.Min = counts[0]
+	s.TxPerBlock.Mean = float64(s.TxCount) / float64(len(counts))
+	s.TxPerBlock.P50 = intPercentile(counts, 50)
+	s.TxPerBlock.P90 = intPercentile(counts, 90)
+	s.TxPerBlock.P99 = intPercentile(counts, 99)
+	s.TxPerBlock.Max = counts[len(counts)-1]
+
+	i := 0
+	for _, bound := range append(txCountBuckets, math.MaxInt32) {
+		n := 0
+		for ; i < len(counts) && counts[i] <= bound; i++ {
+			n++
+		}
+		if bound == math.MaxInt32 {
+			bound = -1
+		}
+		s.TxPerBlockBuckets = append(s.TxPerBlockBuckets, CountBucket{UpperBound: bound, Count: n})
+	}
+
+	if intervals.Count() > 0 {
+		l := newLatency(intervals)
+		s.CommitInterval = &l
+	}
+	s.AvgOurShare = shareTotal * 100 / float64(len(blocks))
+	return s
+}
+
+func (s *BlockSummary) print() {
+	fmt.Println("********Blocks*******")
+	fmt.Printf("block count:%d\n", s.BlockCount)
+	if s.BlocksPerSecond > 0 {
+		fmt.Printf("blocks per second:%.2f\n", s.BlocksPerSecond)
+	}
+	fmt.Printf("tx count:%d our tx count:%d (%.1f%%)\n", s.TxCount, s.OurTxCount, percent(s.OurTxCount, s.TxCount))
+	fmt.Printf("tx per block: min:%d avg:%.2f p50:%d p90:%d p99:%d max:%d\n",
+		s.TxPerBlock.Min, s.TxPerBlock.Mean, s.TxPerBlock.P50, s.TxPerBlock.P90, s.TxPerBlock.P99, s.TxPerBlock.Max)
+	fmt.Println("tx per block distribution:")
+	lower := 0
+	for _, b := range s.TxPerBlockBuckets {
+		if b.Count > 0 {
+			label := fmt.Sprintf("<= %d", b.UpperBound)
+			if b.UpperBound < 0 {
+				label = fmt.Sprintf("> %d", lower)
+			}
+			fmt.Printf("  %-7s %d (%.2f%%)\n", label, b.Count, percent(b.Count, s.BlockCount))
+		}
+		lower = b.UpperBound
+	}
+	if l := s.CommitInterval; l != nil {
+		fmt.Printf("commit interval: min:%fs avg:%fs p50:%fs p90:%fs p99:%fs max:%fs\n",
+			l.Min, l.Mean, l.Percentiles["p50"], l.Percentiles["p90"], l.Percentiles["p99"], l.Max)
+	}
+	fmt.Printf("avg share of our txs per block:%.1f%%\n", s.AvgOurShare)
+	fmt.Printf("blocks without our txs:%d (%.1f%%)\n", s.ZeroOurBlocks, percent(s.ZeroOurBlocks, s.BlockCount))
+}
+
+//intPercentile return the p-th percentile of sorted values
+func intPercentile(sorted []int, p float64) int {
+	i := int(p/100*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+func percent(n int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/shimron/stressingtool/metrics"
+)
+
+//clearScreen move the cursor home and clear the terminal
+const clearScreen = "\033[H\033[2J"
+
+//StartDashboard redraw a view of the run every interval until the returned func is called.
+//With clear the terminal is cleared before every redraw, otherwise the views are printed one after another.
+func (jr *JobRunner) StartDashboard(interval time.Duration, clear bool) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		start := time.Now()
+		last, lastSubmitted, lastConfirmed := start, int64(0), int64(0)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				submitted := atomic.LoadInt64(&jr.live.submitted)
+				confirmed := atomic.LoadInt64(&jr.live.confirmed)
+				view := jr.dashboard(now.Sub(start),
+					float64(submitted-lastSubmitted)/now.Sub(last).Seconds(),
+					float64(confirmed-lastConfirmed)/now.Sub(last).Seconds())
+				if clear {
+					view = clearScreen + view
+				}
+				os.Stdout.WriteString(view)
+				last, lastSubmitted, lastConfirmed = now, submitted, confirmed
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+//dashboard render the live stats, tps and ctps are the submitted and confirmed tps since the last view
+func (jr *JobRunner) dashboard(elapsed time.Duration, tps float64, ctps float64) string {
+	l := jr.live
+	submitted := atomic.LoadInt64(&l.submitted)
+	confirmed := atomic.LoadInt64(&l.confirmed)
+
+	state := "running"
+	select {
+	case <-jr.StopChan:
+		state = "stopping"
+	default:
+	}
+	if stage := jr.currentStage(); stage != "" {
+		state += ", stage " + stage
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "********%s*******\n", jr.Name)
+	fmt.Fprintf(&b, "elapsed:%v (%s)\n", elapsed.Truncate(time.Second), state)
+	fmt.Fprintf(&b, "submitted:%d tps:%.2f avg:%.2f\n", submitted, tps, float64(submitted)/elapsed.Seconds())
+	fmt.Fprintf(&b, "confirmed:%d tps:%.2f avg:%.2f\n", confirmed, ctps, float64(confirmed)/elapsed.Seconds())
+	fmt.Fprintf(&b, "rejected:%d retries:%d in flight:%d unconfirmed:%d\n",
+		atomic.LoadInt64(&l.rejected), atomic.LoadInt64(&l.retries), atomic.LoadInt64(&l.inFlight), jr.Unconfirmed())
+
+	l.failLock.Lock()
+	classes := make([]string, 0, len(l.failed))
+	for class := range l.failed {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	fmt.Fprint(&b, "errors:")
+	for _, class := range classes {
+		fmt.Fprintf(&b, " %s:%d", class, l.failed[class])
+	}
+	l.failLock.Unlock()
+	fmt.Fprintln(&b)
+
+	writeRecent(&b, "execution cost", l.recentExecution.Snapshot())
+	writeRecent(&b, "confirm cost", l.recentConfirm.Snapshot())
+	return b.String()
+}
+
+func writeRecent(b *bytes.Buffer, name string, h *metrics.Histogram) {
+	fmt.Fprintf(b, "%s (last %v): p50:%fs p90:%fs p99:%fs max:%fs\n", name, recentWindow,
+		h.Percentile(50).Seconds(), h.Percentile(90).Seconds(), h.Percentile(99).Seconds(), h.Max().Seconds())
+}
@@ -0,0 +1,123 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shimron/stressingtool/job"
+	"github.com/shimron/stressingtool/metrics"
+)
+
+//liveStats are updated while the run goes, unlike the summary that is only built at the end
+type liveStats struct {
+	submitted    int64
+	confirmed    int64
+	rejected     int64
+	retries      int64
+	inFlight     int64
+	failLock     sync.Mutex
+	failed       map[string]int64 //failure class->count
+	execution    *metrics.Histogram
+	confirmation *metrics.Histogram
+	//recent latencies for the dashboard
+	recentExecution *metrics.Rolling
+	recentConfirm   *metrics.Rolling
+}
+
+//recentWindow is the window of the recent latencies
+const recentWindow = 10 * time.Second
+
+func newLiveStats() *liveStats {
+	return &liveStats{
+		failed:          make(map[string]int64),
+		execution:       metrics.NewHistogram(),
+		confirmation:    metrics.NewHistogram(),
+		recentExecution: metrics.NewRolling(recentWindow, time.Second),
+		recentConfirm:   metrics.NewRolling(recentWindow, time.Second),
+	}
+}
+
+func (l *liveStats) submit() {
+	atomic.AddInt64(&l.submitted, 1)
+	atomic.AddInt64(&l.inFlight, 1)
+}
+
+func (l *liveStats) executed(js *job.JobStat) {
+	atomic.AddInt64(&l.inFlight, -1)
+	cost := js.ExecutedTime.Sub(js.SubmitTime)
+	l.execution.Record(cost)
+	l.recentExecution.Record(cost)
+	atomic.AddInt64(&l.retries, int64(js.Retries))
+	if js.ErrorMsg != "" {
+		l.fail(js.ErrorClass)
+	}
+}
+
+func (l *liveStats) confirm(cost time.Duration) {
+	atomic.AddInt64(&l.confirmed, 1)
+	l.confirmation.Record(cost)
+	l.recentConfirm.Record(cost)
+}
+
+func (l *liveStats) fail(class string) {
+	l.failLock.Lock()
+	l.failed[class]++
+	l.failLock.Unlock()
+}
+
+//serveMetrics serve the live stats on MetricsAddr in the prometheus text format
+func (jr *JobRunner) serveMetrics() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", jr.writeMetrics)
+	fmt.Printf("serving metrics on http://%s/metrics\n", jr.MetricsAddr)
+	if err := http.ListenAndServe(jr.MetricsAddr, mux); err != nil {
+		fmt.Printf("fail to serve metrics:%v\n", err)
+	}
+}
+
+func (jr *JobRunner) writeMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	l := jr.live
+	labels := metrics.Labels{"runner": jr.Name}
+
+	counters := []struct {
+		name  string
+		help  string
+		value int64
+	}{
+		{"stressingtool_jobs_submitted_total", "Jobs sent to the peer.", atomic.LoadInt64(&l.submitted)},
+		{"stressingtool_txs_confirmed_total", "Txs written to ledger.", atomic.LoadInt64(&l.confirmed)},
+		{"stressingtool_txs_rejected_total", "Txs that received a rejection event.", atomic.LoadInt64(&l.rejected)},
+		{"stressingtool_retries_total", "Calls retried by the retry policy.", atomic.LoadInt64(&l.retries)},
+	}
+	for _, c := range counters {
+		metrics.WriteHeader(w, c.name, "counter", c.help)
+		metrics.WriteSample(w, c.name, labels, float64(c.value))
+	}
+
+	metrics.WriteHeader(w, "stressingtool_jobs_failed_total", "counter", "Failed jobs by failure class, rejections included.")
+	l.failLock.Lock()
+	classes := make([]string, 0, len(l.failed))
+	for class := range l.failed {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		metrics.WriteSample(w, "stressingtool_jobs_failed_total", metrics.Labels{"runner": jr.Name, "class": class}, float64(l.failed[class]))
+	}
+	l.failLock.Unlock()
+
+	metrics.WriteHeader(w, "stressingtool_jobs_in_flight", "gauge", "Jobs waiting for the response of the peer.")
+	metrics.WriteSample(w, "stressingtool_jobs_in_flight", labels, float64(atomic.LoadInt64(&l.inFlight)))
+	metrics.WriteHeader(w, "stressingtool_txs_unconfirmed", "gauge", "Submitted invokes neither written to ledger nor rejected yet.")
+	metrics.WriteSample(w, "stressingtool_txs_unconfirmed", labels, float64(jr.Unconfirmed()))
+
+	metrics.WriteHeader(w, "stressingtool_execution_seconds", "histogram", "Time from the submission of a job to the response of the peer.")
+	metrics.WriteHistogram(w, "stressingtool_execution_seconds", labels, l.execution, metrics.DefaultBuckets)
+	metrics.WriteHeader(w, "stressingtool_confirm_seconds", "histogram", "Time from the response of the peer to the commit of the block of the tx.")
+	metrics.WriteHistogram(w, "stressingtool_confirm_seconds", labels, l.confirmation, metrics.DefaultBuckets)
+}
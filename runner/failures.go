@@ -0,0 +1,182 @@
+package runner
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shimron/stressingtool/job"
+)
+
+//Failure is one failed job with the class of its failure
+type Failure struct {
+	JobID      string    `json:"job_id"`
+	Name       string    `json:"name"`
+	Operation  string    `json:"operation"`
+	TXID       string    `json:"txid"`
+	SubmitTime time.Time `json:"submit_time"`
+	Class      string    `json:"class"`
+	Code       int       `json:"code,omitempty"`
+	Message    string    `json:"message"`
+}
+
+//failures are grouped by class, code and message, only the biggest groups are printed
+const (
+	maxFailureGroups  = 20
+	maxFailureSamples = 3
+)
+
+//Failures return every failed job sorted by submit time
+func (jr *JobRunner) Failures() []Failure {
+	jr.States.Lock.RLock()
+	defer jr.States.Lock.RUnlock()
+
+	failures := make([]Failure, 0)
+	for _, js := range jr.States.JobStats {
+		f := Failure{
+			JobID:      js.JobID,
+			Name:       js.Name,
+			Operation:  js.Operation,
+			TXID:       js.TXID,
+			SubmitTime: js.SubmitTime,
+			Class:      js.ErrorClass,
+			Code:       js.ErrorCode,
+			Message:    js.ErrorMsg,
+		}
+		switch txStat := jr.TxStats.Get(js.TXID); {
+		case js.TXID == "":
+			if js.ErrorMsg == "" {
+				continue
+			}
+		case txStat == nil:
+			f.Class = job.ErrUnconfirmed
+			f.Message = "no block or rejection event was received"
+		case txStat.IsSuccess:
+			continue
+		default:
+			f.Class = job.ErrRejected
+		}
+		//results saved before failures were classified
+		if f.Class == "" {
+			f.Class = job.ErrOther
+		}
+		failures = append(failures, f)
+	}
+	sort.Slice(failures, func(i, j int) bool { return failures[i].SubmitTime.Before(failures[j].SubmitTime) })
+	return failures
+}
+
+//FailureSummary count the failures by class and by distinct message
+type FailureSummary struct {
+	Count   int            `json:"count"`
+	Classes map[string]int `json:"classes"`
+	//Groups are the most frequent distinct failures, out of Distinct
+	Groups   []*FailureGroup `json:"groups"`
+	Distinct int             `json:"distinct"`
+}
+
+//FailureGroup is the failures sharing a class, code and message
+type FailureGroup struct {
+	Class        string   `json:"class"`
+	Code         int      `json:"code,omitempty"`
+	Message      string   `json:"message"`
+	Count        int      `json:"count"`
+	SampleJobIDs []string `json:"sample_job_ids"`
+	SampleTXIDs  []string `json:"sample_txids"`
+}
+
+//summarizeFailures count the failures, nil when there is none
+func summarizeFailures(failures []Failure) *FailureSummary {
+	if len(failures) == 0 {
+		return nil
+	}
+	s := &FailureSummary{Count: len(failures), Classes: make(map[string]int)}
+	groups := make(map[string]*FailureGroup)
+	for _, f := range failures {
+		s.Classes[f.Class]++
+		key := fmt.Sprintf("%s\x00%d\x00%s", f.Class, f.Code, f.Message)
+		g, ok := groups[key]
+		if !ok {
+			g = &FailureGroup{Class: f.Class, Code: f.Code, Message: f.Message, SampleJobIDs: []string{}, SampleTXIDs: []string{}}
+			groups[key] = g
+		}
+		g.Count++
+		if len(g.SampleJobIDs) < maxFailureSamples {
+			g.SampleJobIDs = append(g.SampleJobIDs, f.JobID)
+		}
+		if f.TXID != "" && len(g.SampleTXIDs) < maxFailureSamples {
+			g.SampleTXIDs = append(g.SampleTXIDs, f.TXID)
+		}
+	}
+
+	for _, g := range groups {
+		s.Groups = append(s.Groups, g)
+	}
+	sort.Slice(s.Groups, func(i, j int) bool {
+		if s.Groups[i].Count != s.Groups[j].Count {
+			return s.Groups[i].Count > s.Groups[j].Count
+		}
+		return s.Groups[i].Message < s.Groups[j].Message
+	})
+	s.Distinct = len(s.Groups)
+	if len(s.Groups) > maxFailureGroups {
+		s.Groups = s.Groups[:maxFailureGroups]
+	}
+	return s
+}
+
+//print print the failure count of every class and of the most frequent messages
+func (s *FailureSummary) print() {
+	fmt.Println("********Failures*******")
+	names := make([]string, 0, len(s.Classes))
+	for name := range s.Classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s: %d (%.1f%%)\n", name, s.Classes[name], float64(s.Classes[name])*100/float64(s.Count))
+	}
+
+	if s.Distinct > len(s.Groups) {
+		fmt.Printf("%d most frequent of %d distinct failures:\n", len(s.Groups), s.Distinct)
+	}
+	for _, g := range s.Groups {
+		class := g.Class
+		if g.Code != 0 {
+			class = fmt.Sprintf("%s %d", g.Class, g.Code)
+		}
+		fmt.Printf("  [%s] %q: %d sample job ids:%v sample txids:%v\n", class, g.Message, g.Count, g.SampleJobIDs, g.SampleTXIDs)
+	}
+}
+
+//SaveFailures write every failed job to a .json or .csv file
+func (jr *JobRunner) SaveFailures(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	failures := jr.Failures()
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(failures)
+	}
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"job_id", "name", "operation", "txid", "submit_time", "class", "code", "message"})
+	for _, fl := range failures {
+		w.Write([]string{
+			fl.JobID, fl.Name, fl.Operation, fl.TXID, fl.SubmitTime.Format(time.RFC3339Nano),
+			fl.Class, fmt.Sprint(fl.Code), fl.Message,
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
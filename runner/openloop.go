@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shimron/stressingtool/job"
+)
+
+//OpenLoopStats count the jobs that could not be sent on schedule because MaxInFlight jobs were in flight
+type OpenLoopStats struct {
+	//Ran is set when the jobs were sent by the open loop, with a rate, rate stages or a rate saturation search
+	Ran bool `json:"ran,omitempty"`
+	//Dropped jobs were never sent
+	Dropped int64 `json:"dropped"`
+	//Delayed jobs were sent late, once a slot was free
+	Delayed    int64 `json:"delayed"`
+	DelayTotal int64 `json:"delay_total"` //ns
+	DelayMax   int64 `json:"delay_max"`   //ns
+}
+
+//executeOpenLoop send one job every 1/Rate second whatever the response times are.
+//A job due while MaxInFlight jobs are in flight is dropped or sent late, the schedule itself never slips.
+func (jr *JobRunner) executeOpenLoop(jobChan <-chan *job.Job, wg *sync.WaitGroup) {
+	maxInFlight := jr.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = jr.ConcurrencyNum
+	}
+	//every slot is a worker id
+	slots := make(chan int, maxInFlight)
+	for i := 0; i < maxInFlight; i++ {
+		slots <- i
+	}
+	jr.OpenLoopStats.Ran = true
+	jr.setRate(jr.Rate)
+	if len(jr.Stages) > 0 && jr.StageRate {
+		//the rate follows the stages, Rate is not used
+		jr.setRate(jr.Stages[0].From)
+		go jr.runStages(jr.setRate)
+	}
+
+	//when the previous job was due, zero before the first job
+	var last time.Time
+	for {
+		rate := jr.currentRate()
+		if rate <= 0 {
+			//nothing is due at a zero rate, check again a bit later
+			select {
+			case <-time.After(100 * time.Millisecond):
+				last = time.Now()
+				continue
+			case <-jr.StopChan:
+				fmt.Println("stopping job runner")
+				return
+			}
+		}
+
+		due := time.Now()
+		if !last.IsZero() {
+			due = last.Add(time.Duration(float64(time.Second) / rate))
+		}
+		if wait := due.Sub(time.Now()); wait > 0 {
+			//wait in short slices so that a changing rate is picked up
+			if wait > 100*time.Millisecond {
+				wait = 100 * time.Millisecond
+			}
+			select {
+			case <-time.After(wait):
+				continue
+			case <-jr.StopChan:
+				fmt.Println("stopping job runner")
+				return
+			}
+		}
+
+		var jb *job.Job
+		var ok bool
+		select {
+		case jb, ok = <-jobChan:
+			if !ok {
+				fmt.Println("chan was closed")
+				return
+			}
+		case <-jr.StopChan:
+			fmt.Println("stopping job runner")
+			return
+		}
+
+		var worker int
+		select {
+		case worker = <-slots:
+		default:
+			if jr.DropOnSaturation {
+				jr.OpenLoopStats.Dropped++
+				jr.logf("drop job:%s, %d jobs in flight\n", jb.Name, maxInFlight)
+				last = due
+				continue
+			}
+			select {
+			case worker = <-slots:
+			case <-jr.StopChan:
+				fmt.Println("stopping job runner")
+				return
+			}
+			delay := time.Now().Sub(due).Nanoseconds()
+			jr.OpenLoopStats.Delayed++
+			jr.OpenLoopStats.DelayTotal += delay
+			if delay > jr.OpenLoopStats.DelayMax {
+				jr.OpenLoopStats.DelayMax = delay
+			}
+		}
+
+		wg.Add(1)
+		jr.logf("receive new job:%s\n", jb.Name)
+		go func(jb *job.Job, worker int) {
+			defer wg.Done()
+			jr.work(jb, worker)
+			slots <- worker
+		}(jb, worker)
+		last = due
+	}
+}
+
+func (jr *JobRunner) setRate(rate float64) {
+	atomic.StoreUint64(&jr.rate, math.Float64bits(rate))
+}
+
+func (jr *JobRunner) currentRate() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&jr.rate))
+}
@@ -0,0 +1,153 @@
+package runner
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shimron/stressingtool/job"
+)
+
+//Results is what a run leaves behind, enough to rebuild its summary later
+type Results struct {
+	Config         json.RawMessage `json:"config,omitempty"`
+	Name           string          `json:"name"`
+	EventAddr      string          `json:"event_addr"`
+	ConcurrencyNum int             `json:"concurrency_num"`
+	Rate           float64         `json:"rate,omitempty"`
+	OpenLoopStats  OpenLoopStats   `json:"open_loop_stats"`
+	StageRate      bool            `json:"stage_rate,omitempty"`
+	StageMarks     []StageMark     `json:"stage_marks,omitempty"`
+	Saturation     *Saturation     `json:"saturation,omitempty"`
+	StartTime      time.Time       `json:"start_time"`
+	StopTime       time.Time       `json:"stop_time"`
+	EndTime        time.Time       `json:"end_time"`
+	JobStats       []*job.JobStat  `json:"job_stats"`
+	Blocks         []Block         `json:"blocks"`
+}
+
+//Results return the recorded job stats of the runner ordered by submit time
+func (jr *JobRunner) Results() *Results {
+	jr.States.Lock.RLock()
+	stats := make([]*job.JobStat, 0, len(jr.States.JobStats))
+	for _, js := range jr.States.JobStats {
+		stats = append(stats, js)
+	}
+	jr.States.Lock.RUnlock()
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].SubmitTime.Before(stats[j].SubmitTime)
+	})
+
+	return &Results{
+		Config:         jr.Config,
+		Name:           jr.Name,
+		EventAddr:      jr.EventAddr,
+		ConcurrencyNum: jr.ConcurrencyNum,
+		Rate:           jr.Rate,
+		OpenLoopStats:  jr.OpenLoopStats,
+		StageRate:      jr.StageRate,
+		StageMarks:     jr.StageMarks,
+		Saturation:     jr.Saturation,
+		StartTime:      jr.StartTime,
+		StopTime:       jr.StopTime,
+		EndTime:        jr.EndTime,
+		JobStats:       stats,
+		Blocks:         jr.Blocks,
+	}
+}
+
+//SaveResults write the results of the runner to a json file
+func (jr *JobRunner) SaveResults(path string) error {
+	b, err := json.MarshalIndent(jr.Results(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+//SaveJobs write the raw stat of every job ordered by submit time,
+//as json lines for a .jsonl file, as a json array for a .json file like the failures, and as csv otherwise
+func (jr *JobRunner) SaveJobs(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stats := jr.Results().JobStats
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl":
+		enc := json.NewEncoder(f)
+		for _, js := range stats {
+			if err := enc.Encode(js); err != nil {
+				return err
+			}
+		}
+		return nil
+	case ".json":
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+
+	w := csv.NewWriter(f)
+	w.Write([]string{
+		"job_id", "name", "operation", "function", "chaincode_id", "tags", "identity", "stage", "txid",
+		"submit_time", "executed_time", "tx_confirmed_time", "is_success", "is_done", "error_msg", "error_class", "error_code", "retries",
+	})
+	for _, js := range stats {
+		w.Write([]string{
+			js.JobID, js.Name, js.Operation, js.Function, js.ChaincodeID, strings.Join(js.Tags, ";"), js.Identity, js.Stage, js.TXID,
+			formatTime(js.SubmitTime), formatTime(js.ExecutedTime), formatTime(js.TXConfirmedTime),
+			strconv.FormatBool(js.IsSuccess), strconv.FormatBool(js.IsDone), js.ErrorMsg, js.ErrorClass, strconv.Itoa(js.ErrorCode), strconv.Itoa(js.Retries),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+//formatTime format a time for csv, a zero time is left empty
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+//LoadResults rebuild a finished JobRunner from a results file written by SaveResults
+func LoadResults(path string) (*JobRunner, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var res Results
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+
+	jr := NewJobRunner(res.Name, res.ConcurrencyNum, res.EventAddr)
+	jr.Config = res.Config
+	jr.StartTime = res.StartTime
+	jr.StopTime = res.StopTime
+	jr.EndTime = res.EndTime
+	jr.Rate = res.Rate
+	jr.OpenLoopStats = res.OpenLoopStats
+	jr.StageRate = res.StageRate
+	jr.StageMarks = res.StageMarks
+	jr.Saturation = res.Saturation
+	jr.Blocks = res.Blocks
+	for _, js := range res.JobStats {
+		jr.States.Set(js)
+		//only txs that received a block or rejection event were kept in TxStats
+		if js.TXID != "" && js.IsDone {
+			jr.TxStats.Set(js)
+		}
+	}
+	return jr, nil
+}
@@ -1,11 +1,13 @@
 package runner
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"os"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/shimron/stressingtool/cache"
@@ -17,6 +19,8 @@ import (
 
 //JobRunner ...
 type JobRunner struct {
+	//Config is the json of the configuration the runner was built from, saved with the results
+	Config         json.RawMessage
 	Name           string
 	EventAddr      string
 	States         *cache.JobStatMap
@@ -26,8 +30,62 @@ type JobRunner struct {
 	IsStopped      bool
 	StartTime      time.Time
 	StopTime       time.Time
+	EndTime        time.Time
 	NoEventChan    chan struct{}
 	once           sync.Once
+	//executedChan is closed once all jobs were executed
+	executedChan chan struct{}
+	//listenStopChan stop the block listener, see stopListening
+	listenStopChan chan struct{}
+	listenOnce     sync.Once
+
+	//Rate switch to open loop: jobs are sent at Rate jobs per second whatever the response times are
+	Rate float64
+	//MaxInFlight cap the jobs in flight in open loop, ConcurrencyNum is used when it is 0
+	MaxInFlight int
+	//DropOnSaturation drop the jobs due while MaxInFlight jobs are in flight, instead of delaying them
+	DropOnSaturation bool
+	OpenLoopStats    OpenLoopStats
+
+	//Stages make a load profile, the run stops after the last stage
+	Stages []Stage
+	//StageRate make the stages target the rate of an open loop instead of the concurrency
+	StageRate  bool
+	StageMarks []StageMark
+	stageLock  sync.Mutex
+	stage      int32
+	rate       uint64 //bits of the current open loop rate
+
+	//Saturation search the peak sustainable throughput, its steps replace Stages
+	Saturation *Saturation
+	//Duration stop the run after it, 0 runs until the job channel is closed
+	Duration    time.Duration
+	stopLock    sync.Mutex
+	unconfirmed int64
+
+	//confirmLock guard pending and early, and the IsDone, IsSuccess and TXConfirmedTime of the jobstats
+	confirmLock sync.Mutex
+	pending     map[string]bool    //txid->submitted invoke neither written to ledger nor rejected yet
+	early       map[string]txEvent //txid->event received before the job of the tx was registered
+	earlySwept  time.Time
+
+	waitLock sync.Mutex
+	waiters  map[string]chan struct{} //txid->closed once the tx is written to ledger or rejected
+
+	//Blocks are all the blocks received by the block listener
+	Blocks    []Block
+	blockLock sync.Mutex
+
+	//Verbose print a line for every job, block and rejection
+	Verbose bool
+
+	//Identity return the secureContext of the jobs of a worker, the one of the job is kept when it is nil.
+	//Workers are the ConcurrencyNum slots of the closed loop, the stage targets, or the MaxInFlight slots of the open loop.
+	Identity func(worker int) string
+
+	//MetricsAddr serve live metrics on http://<MetricsAddr>/metrics during the run when it is set
+	MetricsAddr string
+	live        *liveStats
 }
 
 //NewJobRunner create a new JobRunner
@@ -43,9 +101,15 @@ func NewJobRunner(name string, concurrencyNum int, eventAddr string) *JobRunner
 		EventAddr:      eventAddr,
 		StopChan:       make(chan struct{}),
 		NoEventChan:    make(chan struct{}),
+		executedChan:   make(chan struct{}),
+		listenStopChan: make(chan struct{}),
 		States:         cache.NewJobStatMap(),
 		TxStats:        cache.NewTxStatMap(),
 		once:           sync.Once{},
+		waiters:        make(map[string]chan struct{}),
+		pending:        make(map[string]bool),
+		early:          make(map[string]txEvent),
+		live:           newLiveStats(),
 	}
 }
 
@@ -53,53 +117,274 @@ func NewJobRunner(name string, concurrencyNum int, eventAddr string) *JobRunner
 func (jr *JobRunner) Execute(jobChan <-chan *job.Job) {
 
 	jr.once.Do(func() {
+		if jr.MetricsAddr != "" {
+			go jr.serveMetrics()
+		}
 		go jr.listenBlock(jr.EventAddr)
 		time.Sleep(1 * time.Second)
 
+		if jr.Saturation != nil {
+			jr.Stages = jr.Saturation.Stages()
+			jr.Saturation.Knee = -1
+		}
 		jr.StartTime = time.Now()
-
-		ticks := make(chan struct{}, jr.ConcurrencyNum)
-		for i := 0; i < jr.ConcurrencyNum; i++ {
-			ticks <- struct{}{}
+		if jr.Duration > 0 {
+			go func() {
+				select {
+				case <-time.After(jr.Duration):
+					fmt.Printf("run duration %v reached\n", jr.Duration)
+					jr.Stop()
+				case <-jr.StopChan:
+				}
+			}()
 		}
 
 		var wg sync.WaitGroup
-	loop:
-		for {
-			select {
-			case jb, ok := <-jobChan:
-				if !ok {
-					fmt.Println("chan was closed")
-					break loop
-				}
-				wg.Add(1)
-				<-ticks
-				fmt.Printf("receive new job:%s\n", jb.Name)
-				go func(jb *job.Job) {
-					defer wg.Done()
-					js := jb.Run()
-					fmt.Printf("%s has done\n", jb.Name)
-					err := jr.States.Set(js)
-					if err != nil {
-						fmt.Printf("fail to set jobstat:%v\n", err)
-					}
-					ticks <- struct{}{}
-				}(jb)
-			case <-jr.StopChan:
-				fmt.Printf("stopping job runner")
-				break loop
-			}
-			runtime.Gosched()
+		if jr.Rate > 0 || (len(jr.Stages) > 0 && jr.StageRate) {
+			jr.executeOpenLoop(jobChan, &wg)
+		} else {
+			jr.executeClosedLoop(jobChan, &wg)
 		}
 		fmt.Println("waiting for jobs to be done...")
 		wg.Wait()
 		fmt.Println("all jobs were executed")
 		jr.StopTime = time.Now()
+		close(jr.executedChan)
 	},
 	)
 
 }
 
+//executeClosedLoop send a new job only when one of the ConcurrencyNum slots is free
+func (jr *JobRunner) executeClosedLoop(jobChan <-chan *job.Job, wg *sync.WaitGroup) {
+	ticks := newTokenPool(jr.ConcurrencyNum, jr.ConcurrencyNum)
+	if len(jr.Stages) > 0 {
+		//the concurrency follows the stages, ConcurrencyNum is not used
+		ticks = newTokenPool(int(math.Ceil(maxTarget(jr.Stages))), int(math.Ceil(jr.Stages[0].From)))
+		go jr.runStages(func(target float64) {
+			ticks.setLimit(int(math.Ceil(target)))
+		})
+	}
+
+loop:
+	for {
+		select {
+		case jb, ok := <-jobChan:
+			if !ok {
+				fmt.Println("chan was closed")
+				break loop
+			}
+			worker, ok := ticks.acquire(jr.StopChan)
+			if !ok {
+				fmt.Println("stopping job runner")
+				break loop
+			}
+			wg.Add(1)
+			jr.logf("receive new job:%s\n", jb.Name)
+			go func(jb *job.Job) {
+				defer wg.Done()
+				jr.work(jb, worker)
+				ticks.release(worker)
+			}(jb)
+		case <-jr.StopChan:
+			fmt.Println("stopping job runner")
+			break loop
+		}
+		runtime.Gosched()
+	}
+}
+
+//work run a job, and the following steps if the job is part of a workflow, on the worker with the given id
+func (jr *JobRunner) work(jb *job.Job, worker int) {
+	if jb.Bind != nil {
+		bound, err := jb.Bind(worker)
+		if err != nil {
+			fmt.Printf("fail to bind %s to worker %d:%v\n", jb.Name, worker, err)
+			return
+		}
+		jb = bound
+	}
+	//the steps of a workflow run one after another on the same worker
+	for jb != nil {
+		if jr.Identity != nil {
+			jb.Command.SecureContext = jr.Identity(worker)
+		}
+		js := jr.runJob(jb)
+		jb = jr.nextStep(jb, js)
+	}
+}
+
+func (jr *JobRunner) runJob(jb *job.Job) *job.JobStat {
+	stage := jr.currentStage()
+	jr.live.submit()
+	js := jb.Run()
+	jr.live.executed(js)
+	js.Stage = stage
+	jr.logf("%s has done\n", jb.Name)
+	jr.register(js)
+	return js
+}
+
+//txEvent is the block or rejection event of a tx
+type txEvent struct {
+	//time is the commit time of the block, or when the rejection was received
+	time     time.Time
+	rejected bool
+	errorMsg string
+	received time.Time
+}
+
+//earlyEventTTL is how long the event of an unknown tx is kept, the txs of other clients are never registered
+const earlyEventTTL = time.Minute
+
+//register save the jobstat, the tx of a submitted invoke is pending until its event settles it
+func (jr *JobRunner) register(js *job.JobStat) {
+	jr.confirmLock.Lock()
+	defer jr.confirmLock.Unlock()
+	if err := jr.States.Set(js); err != nil {
+		fmt.Printf("fail to set jobstat:%v\n", err)
+	}
+	if js.TXID == "" || js.IsDone {
+		return
+	}
+	//the event may have been received before the job returned the txid
+	if ev, ok := jr.early[js.TXID]; ok {
+		delete(jr.early, js.TXID)
+		jr.confirm(js, ev)
+		return
+	}
+	jr.pending[js.TXID] = true
+	atomic.AddInt64(&jr.unconfirmed, 1)
+}
+
+//settle confirm the tx of an event, true if it is a pending tx of the run.
+//The event of a tx that is not registered yet is kept until its job registers it, a second event of a tx is ignored.
+func (jr *JobRunner) settle(txid string, ev txEvent) bool {
+	jr.confirmLock.Lock()
+	defer jr.confirmLock.Unlock()
+	if !jr.pending[txid] {
+		if jr.States.GetJobStatByTXID(txid) == nil {
+			jr.logf("jobstat not found for %s, keep its event\n", txid)
+			if _, ok := jr.early[txid]; !ok {
+				ev.received = time.Now()
+				jr.early[txid] = ev
+			}
+			jr.sweepEarly()
+		}
+		return false
+	}
+	delete(jr.pending, txid)
+	atomic.AddInt64(&jr.unconfirmed, -1)
+	jr.confirm(jr.States.GetJobStatByTXID(txid), ev)
+	return true
+}
+
+//sweepEarly drop the events of unknown txs that were kept longer than earlyEventTTL, confirmLock must be held
+func (jr *JobRunner) sweepEarly() {
+	if time.Since(jr.earlySwept) < earlyEventTTL {
+		return
+	}
+	jr.earlySwept = time.Now()
+	for txid, ev := range jr.early {
+		if time.Since(ev.received) > earlyEventTTL {
+			delete(jr.early, txid)
+		}
+	}
+}
+
+//confirm apply the event to the jobstat of its tx, confirmLock must be held
+func (jr *JobRunner) confirm(js *job.JobStat, ev txEvent) {
+	js.IsDone = true
+	js.TXConfirmedTime = ev.time
+	if ev.rejected {
+		js.IsSuccess = false
+		js.ErrorMsg = ev.errorMsg
+		js.ErrorClass = job.ErrRejected
+		atomic.AddInt64(&jr.live.rejected, 1)
+		jr.live.fail(job.ErrRejected)
+	} else {
+		js.IsSuccess = true
+		jr.live.confirm(ev.time.Sub(js.ExecutedTime))
+	}
+	jr.TxStats.Set(js)
+	jr.notifyTx(js.TXID)
+}
+
+//nextStep return the job following jb in its workflow, or nil when the workflow is over or has to be aborted
+func (jr *JobRunner) nextStep(jb *job.Job, js *job.JobStat) *job.Job {
+	if jb.Flow == nil {
+		return nil
+	}
+	defer func() {
+		js.Result = ""
+	}()
+	//the block listener writes the rejection of an invoke
+	jr.confirmLock.Lock()
+	errorMsg := js.ErrorMsg
+	jr.confirmLock.Unlock()
+	if errorMsg != "" {
+		jr.logf("%s failed, abort its workflow:%s\n", jb.Name, errorMsg)
+		return nil
+	}
+	if jb.WaitCommit && js.TXID != "" {
+		txStat := jr.WaitTx(js.TXID, jb.WaitTimeout)
+		if txStat == nil {
+			jr.logf("%s was not written to ledger in %v, abort its workflow\n", jb.Name, jb.WaitTimeout)
+			return nil
+		}
+		if !txStat.IsSuccess {
+			jr.logf("%s was rejected, abort its workflow\n", jb.Name)
+			return nil
+		}
+	}
+	next, err := jb.Flow.Next(js)
+	if err != nil {
+		jr.logf("fail to build the step after %s, abort its workflow:%v\n", jb.Name, err)
+		return nil
+	}
+	return next
+}
+
+//WaitTx wait until the tx is written to ledger or rejected and return its stat, or nil on timeout
+func (jr *JobRunner) WaitTx(txid string, timeout time.Duration) *job.JobStat {
+	jr.waitLock.Lock()
+	ch, ok := jr.waiters[txid]
+	if !ok {
+		ch = make(chan struct{})
+		jr.waiters[txid] = ch
+	}
+	jr.waitLock.Unlock()
+	defer func() {
+		jr.waitLock.Lock()
+		delete(jr.waiters, txid)
+		jr.waitLock.Unlock()
+	}()
+
+	//the event may have been received before the waiter was registered
+	if txStat := jr.TxStats.Get(txid); txStat != nil {
+		return txStat
+	}
+	select {
+	case <-ch:
+		return jr.TxStats.Get(txid)
+	case <-time.After(timeout):
+		return nil
+	}
+}
+
+//notifyTx wake up the waiter of the tx, if any
+func (jr *JobRunner) notifyTx(txid string) {
+	jr.waitLock.Lock()
+	defer jr.waitLock.Unlock()
+	if ch, ok := jr.waiters[txid]; ok {
+		close(ch)
+		delete(jr.waiters, txid)
+	}
+}
+
+//eventIdleTimeout is how long the block listener waits for an event once all jobs were executed
+const eventIdleTimeout = 20 * time.Second
+
 func (jr *JobRunner) listenBlock(url string) {
 	ec := event.NewEventClient(url)
 	if ec == nil {
@@ -114,23 +399,18 @@ loop:
 			wg.Add(1)
 			go func(b *pb.Event_Block) {
 				defer wg.Done()
-				if len(b.Block.Transactions) != 0 {
+				blockTimestamp := b.Block.GetNonHashData().GetLocalLedgerCommitTimestamp()
+				blockTime := time.Unix(blockTimestamp.Seconds, int64(blockTimestamp.Nanos))
+				block := Block{CommitTime: blockTime, ReceivedTime: time.Now(), TxCount: len(b.Block.Transactions)}
+				defer func() { jr.addBlock(block) }()
 
-					blockTimestamp := b.Block.GetNonHashData().GetLocalLedgerCommitTimestamp()
-					blockTime := time.Unix(blockTimestamp.Seconds, int64(blockTimestamp.Nanos))
+				if len(b.Block.Transactions) != 0 {
 
 					for _, tx := range b.Block.Transactions {
-						fmt.Printf("%s was written to ledger\n", tx.Txid)
-						//	time.Sleep(2 * time.Second)
-						js := jr.States.GetJobStatByTXID(tx.Txid)
-						if js == nil {
-							fmt.Printf("jobstat not found for %s\n", tx.Txid)
-							continue
+						jr.logf("%s was written to ledger\n", tx.Txid)
+						if jr.settle(tx.Txid, txEvent{time: blockTime}) {
+							block.OurTxCount++
 						}
-						js.IsDone = true
-						js.IsSuccess = true
-						js.TXConfirmedTime = blockTime
-						jr.TxStats.Set(js)
 					}
 				}
 			}(b)
@@ -139,32 +419,39 @@ loop:
 			wg.Add(1)
 			go func(r *pb.Event_Rejection) {
 				defer wg.Done()
-				fmt.Printf("%s was rejected\n", r.Rejection.Tx.Txid)
-				//	time.Sleep(2 * time.Second)
-				js := jr.States.GetJobStatByTXID(r.Rejection.Tx.Txid)
-				if js == nil {
-					fmt.Printf("jobstat not found for %s\n", r.Rejection.Tx.Txid)
-					return
-				}
-				js.IsSuccess = false
-				js.IsDone = true
-				js.TXConfirmedTime = time.Now()
-				js.ErrorMsg = r.Rejection.ErrorMsg
-				jr.TxStats.Set(js)
-
+				jr.logf("%s was rejected\n", r.Rejection.Tx.Txid)
+				jr.settle(r.Rejection.Tx.Txid, txEvent{time: time.Now(), rejected: true, errorMsg: r.Rejection.ErrorMsg})
 			}(r)
 
-		case <-time.After(20 * time.Second):
+		case <-jr.listenStopChan:
 			break loop
+
+		case <-time.After(eventIdleTimeout):
+			//a quiet period while jobs are still being sent does not end the listener
+			select {
+			case <-jr.executedChan:
+				break loop
+			default:
+			}
 		}
 		runtime.Gosched()
 	}
 	wg.Wait()
-	jr.NoEventChan <- struct{}{}
+	//closed rather than sent to, nobody may be reading it
+	close(jr.NoEventChan)
+}
+
+//logf print the per job lines, only in verbose mode
+func (jr *JobRunner) logf(format string, a ...interface{}) {
+	if jr.Verbose {
+		fmt.Printf(format, a...)
+	}
 }
 
 //Stop stop job runner
 func (jr *JobRunner) Stop() {
+	jr.stopLock.Lock()
+	defer jr.stopLock.Unlock()
 	if jr.IsStopped {
 		fmt.Printf("runner has been stopped")
 		return
@@ -173,101 +460,41 @@ func (jr *JobRunner) Stop() {
 	close(jr.StopChan)
 }
 
-//CollectStates caculate summary info
-func (jr *JobRunner) CollectStates() {
-	totalTimeCost := time.Now().Sub(jr.StartTime).Nanoseconds()
-	totalSubmitTimeCost := jr.StopTime.Sub(jr.StartTime).Nanoseconds()
-	jobCount := len(jr.States.JobStats)
-	//save 10 failed job name ( only used to  validate  transactions were failed exactly )
-	var failedJobs = make([]string, 0, 10)
-	var successCount int
-	var failedCount int
-	var finishedCount int
-	var avgExecutionCost float64
-	var avgConfirmCost float64
-	var minExecutionCost float64
-	var minConfirmCost float64
-	var maxExecutionCost float64
-	var maxConfirmCost float64
-
-	var totalExecutionCost int64
-	var totalConfirmCost int64
-	for _, jb := range jr.States.JobStats {
-		executionCost := jb.ExecutedTime.Sub(jb.SubmitTime).Nanoseconds()
-		if executionCost > 0 {
-			totalExecutionCost += executionCost
-			if minExecutionCost != 0 {
-				minExecutionCost = math.Min(float64(minExecutionCost), float64(executionCost))
-			} else {
-				minExecutionCost = float64(executionCost)
-			}
-			if maxExecutionCost != 0 {
-				maxExecutionCost = math.Max(float64(maxExecutionCost), float64(executionCost))
-			} else {
-				maxExecutionCost = float64(executionCost)
-			}
-		}
+//Unconfirmed return the number of submitted invokes that were neither written to ledger nor rejected yet
+func (jr *JobRunner) Unconfirmed() int64 {
+	return atomic.LoadInt64(&jr.unconfirmed)
+}
 
-		if len(jb.TXID) == 0 {
-			successCount++
-			finishedCount++
-			continue
-		}
-		//未找到对应的txid对应的job stat，认为任务失败
-		txStat := jr.TxStats.Get(jb.TXID)
-		if txStat == nil {
-			failedCount++
-			if len(failedJobs) < cap(failedJobs) {
-				failedJobs = append(failedJobs, jb.Name)
-			}
-			continue
+//Drain wait until every submitted invoke was written to ledger or rejected,
+//the block listener gave up after eventIdleTimeout without events once all jobs were executed, or timeout.
+//The block listener is stopped when it returns, so the job stats can be read without confirmLock.
+func (jr *JobRunner) Drain(timeout time.Duration) {
+	defer jr.stopListening()
+	deadline := time.Now().Add(timeout)
+	for {
+		n := jr.Unconfirmed()
+		if n <= 0 {
+			fmt.Println("all txs were confirmed")
+			return
 		}
-
-		finishedCount++
-		//收到tx的block event认定为成功，收到rejection event认定为失败
-		if txStat.IsSuccess {
-			successCount++
-		} else {
-			failedCount++
-			if len(failedJobs) < cap(failedJobs) {
-				failedJobs = append(failedJobs, jb.Name)
-			}
-			continue
+		if time.Now().After(deadline) {
+			fmt.Printf("%d txs are still unconfirmed after %v\n", n, timeout)
+			return
 		}
-		//仅计算写入ledger的交易确认时间
-		confirmCost := txStat.TXConfirmedTime.Sub(jb.ExecutedTime).Nanoseconds()
-		if confirmCost > 0 {
-			totalConfirmCost += confirmCost
-
-			if minConfirmCost != 0 {
-				minConfirmCost = math.Min(float64(minConfirmCost), float64(confirmCost))
-			} else {
-				minConfirmCost = float64(confirmCost)
-			}
-
-			if maxConfirmCost != 0 {
-				maxConfirmCost = math.Max(float64(maxConfirmCost), float64(confirmCost))
-			} else {
-				maxConfirmCost = float64(confirmCost)
-			}
+		fmt.Printf("waiting for %d txs to be confirmed...\n", n)
+		select {
+		case <-time.After(1 * time.Second):
+		case <-jr.NoEventChan:
+			fmt.Printf("no more events, %d txs are unconfirmed\n", jr.Unconfirmed())
+			return
 		}
 	}
+}
 
-	avgExecutionCost = float64(totalExecutionCost) / float64(jobCount)
-	avgConfirmCost = float64(totalConfirmCost) / float64(successCount)
-
-	fmt.Println("********Summary*******")
-	fmt.Printf("total job count:%d\n", jobCount)
-	fmt.Printf("total time cost:%fs\n", float64(totalTimeCost)/1000000000)
-	fmt.Printf("total job execution time cost:%fs\n", float64(totalSubmitTimeCost)/1000000000)
-	fmt.Printf("finished job count:%d\n", finishedCount)
-	fmt.Printf("successful job count:%d\n", successCount)
-	fmt.Printf("failed job count:%d\n", failedCount)
-	fmt.Printf("min execution cost:%fs\n", minExecutionCost/1000000000)
-	fmt.Printf("max execution cost:%fs\n", maxExecutionCost/1000000000)
-	fmt.Printf("avg execution cost:%fs\n", avgExecutionCost/1000000000)
-	fmt.Printf("min confirm cost:%fs\n", minConfirmCost/1000000000)
-	fmt.Printf("max confirm cost:%fs\n", maxConfirmCost/1000000000)
-	fmt.Printf("avg confirm cost:%fs\n", avgConfirmCost/1000000000)
-	fmt.Printf("first 10 failed job names:%v\n", failedJobs)
+//stopListening stop the block listener and wait until it has applied the events it received
+func (jr *JobRunner) stopListening() {
+	jr.listenOnce.Do(func() {
+		close(jr.listenStopChan)
+	})
+	<-jr.NoEventChan
 }
@@ -0,0 +1,144 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+)
+
+//Saturation raise the concurrency (or the rate with StageRate) step by step until the throughput flattens,
+//the confirm latency passes MaxLatency or the rejection rate passes MaxRejectionRate
+type Saturation struct {
+	Start        float64       `json:"start"`
+	Step         float64       `json:"step"`
+	Max          float64       `json:"max"`
+	StepDuration time.Duration `json:"step_duration"`
+	//MinGain is the relative throughput gain below which the throughput is flat, e.g. 0.05
+	MinGain          float64       `json:"min_gain"`
+	MaxLatency       time.Duration `json:"max_latency"`
+	MaxRejectionRate float64       `json:"max_rejection_rate"`
+
+	//Points is the measured throughput/latency curve, one point per step
+	Points []SaturationPoint `json:"points"`
+	//Knee is the index in Points of the peak sustainable step, -1 before the first step
+	Knee int `json:"knee"`
+	//Reason tell why the search stopped
+	Reason string `json:"reason"`
+}
+
+//SaturationPoint is the measurement of one step
+type SaturationPoint struct {
+	Target float64 `json:"target"`
+	//Throughput is confirmed txs (or successful queries) per second during the step
+	Throughput    float64       `json:"throughput"`
+	AvgLatency    time.Duration `json:"avg_latency"`
+	MaxLatency    time.Duration `json:"max_latency"`
+	Confirmed     int           `json:"confirmed"`
+	Rejected      int           `json:"rejected"`
+	RejectionRate float64       `json:"rejection_rate"`
+}
+
+//Stages return one constant stage per step from Start to Max
+func (s *Saturation) Stages() []Stage {
+	var stages []Stage
+	for target := s.Start; target <= s.Max; target += s.Step {
+		stages = append(stages, Stage{
+			Name:     fmt.Sprintf("step%d", len(stages)+1),
+			Duration: s.StepDuration,
+			From:     target,
+			To:       target,
+		})
+	}
+	return stages
+}
+
+//measureStep measure an ended step, true if the search is over.
+//Confirmations are counted by the time they were written to ledger, so the point reflects the load of the step.
+func (jr *JobRunner) measureStep(mark StageMark) bool {
+	s := jr.Saturation
+	p := SaturationPoint{Target: mark.To}
+	var totalLatency time.Duration
+
+	//the listener confirms the jobstats under confirmLock
+	jr.confirmLock.Lock()
+	jr.States.Lock.RLock()
+	for _, js := range jr.States.JobStats {
+		var done time.Time
+		var latency time.Duration
+		var success bool
+		if js.TXID != "" {
+			if !js.IsDone {
+				continue
+			}
+			done, latency, success = js.TXConfirmedTime, js.TXConfirmedTime.Sub(js.ExecutedTime), js.IsSuccess
+		} else {
+			done, latency, success = js.ExecutedTime, js.ExecutedTime.Sub(js.SubmitTime), js.ErrorMsg == ""
+		}
+		if done.Before(mark.Start) || done.After(mark.End) {
+			continue
+		}
+		if !success {
+			p.Rejected++
+			continue
+		}
+		p.Confirmed++
+		totalLatency += latency
+		if latency > p.MaxLatency {
+			p.MaxLatency = latency
+		}
+	}
+	jr.States.Lock.RUnlock()
+	jr.confirmLock.Unlock()
+
+	if seconds := mark.End.Sub(mark.Start).Seconds(); seconds > 0 {
+		p.Throughput = float64(p.Confirmed) / seconds
+	}
+	if p.Confirmed > 0 {
+		p.AvgLatency = totalLatency / time.Duration(p.Confirmed)
+	}
+	if n := p.Confirmed + p.Rejected; n > 0 {
+		p.RejectionRate = float64(p.Rejected) / float64(n)
+	}
+	s.Points = append(s.Points, p)
+	last := len(s.Points) - 1
+	fmt.Printf("saturation %s: target:%.2f throughput:%.2f/s avg latency:%v rejection rate:%.2f%%\n",
+		mark.Name, p.Target, p.Throughput, p.AvgLatency, p.RejectionRate*100)
+
+	switch {
+	case s.MaxLatency > 0 && p.AvgLatency > s.MaxLatency:
+		s.Reason = fmt.Sprintf("avg latency %v passed %v", p.AvgLatency, s.MaxLatency)
+		s.Knee = last - 1
+	case s.MaxRejectionRate > 0 && p.RejectionRate > s.MaxRejectionRate:
+		s.Reason = fmt.Sprintf("rejection rate %.2f%% passed %.2f%%", p.RejectionRate*100, s.MaxRejectionRate*100)
+		s.Knee = last - 1
+	case last > 0 && p.Throughput < s.Points[s.Knee].Throughput*(1+s.MinGain):
+		s.Reason = fmt.Sprintf("throughput gained less than %.1f%%", s.MinGain*100)
+	default:
+		//the throughput still grows, this step is the best so far
+		s.Knee = last
+		if last == len(jr.Stages)-1 {
+			s.Reason = "max target reached"
+		}
+		return false
+	}
+	return true
+}
+
+//print print the throughput/latency curve and the knee point
+func (s *Saturation) print() {
+	fmt.Println("********Saturation*******")
+	fmt.Println("target\tthroughput/s\tavg latency\tmax latency\trejection rate")
+	for i, p := range s.Points {
+		mark := ""
+		if i == s.Knee {
+			mark = "\t<- knee"
+		}
+		fmt.Printf("%.2f\t%.2f\t%v\t%v\t%.2f%%%s\n", p.Target, p.Throughput, p.AvgLatency, p.MaxLatency, p.RejectionRate*100, mark)
+	}
+	fmt.Printf("stopped because:%s\n", s.Reason)
+	if s.Knee < 0 {
+		fmt.Println("no sustainable step was found")
+		return
+	}
+	knee := s.Points[s.Knee]
+	fmt.Printf("peak sustainable throughput:%.2f/s at target %.2f, avg latency %v\n", knee.Throughput, knee.Target, knee.AvgLatency)
+}
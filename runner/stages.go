@@ -0,0 +1,215 @@
+package runner
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//Stage is one timed part of a load profile, the target moves linearly from From to To over Duration.
+//The target is the concurrency in closed loop and the rate (jobs/s) in open loop.
+type Stage struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	From     float64       `json:"from"`
+	To       float64       `json:"to"`
+}
+
+//StageMark record when a stage actually ran
+type StageMark struct {
+	Stage
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+//target return the target of the stage after elapsed
+func (st Stage) target(elapsed time.Duration) float64 {
+	if st.Duration <= 0 || elapsed >= st.Duration {
+		return st.To
+	}
+	return st.From + (st.To-st.From)*float64(elapsed)/float64(st.Duration)
+}
+
+//maxTarget return the highest target of all stages
+func maxTarget(stages []Stage) float64 {
+	var max float64
+	for _, st := range stages {
+		max = math.Max(max, math.Max(st.From, st.To))
+	}
+	return max
+}
+
+//runStages move the target through the stages and stop the runner after the last one
+func (jr *JobRunner) runStages(setTarget func(float64)) {
+	const step = 200 * time.Millisecond
+
+	//a saturation step is measured while the next one runs, once the events of the txs committed at its end have arrived
+	var measuring *StageMark
+	for i, st := range jr.Stages {
+		start := time.Now()
+		jr.markStage(i, start)
+		fmt.Printf("stage %s started, target %.2f -> %.2f in %v\n", st.Name, st.From, st.To, st.Duration)
+		for {
+			elapsed := time.Now().Sub(start)
+			setTarget(st.target(elapsed))
+			if elapsed >= st.Duration {
+				break
+			}
+			wait := st.Duration - elapsed
+			if wait > step {
+				wait = step
+			}
+			select {
+			case <-time.After(wait):
+			case <-jr.StopChan:
+				jr.endStage(time.Now())
+				return
+			}
+		}
+		mark := jr.endStage(time.Now())
+		if jr.Saturation == nil {
+			continue
+		}
+		if measuring != nil && jr.measureStep(*measuring) {
+			fmt.Println("saturation search is over")
+			jr.Stop()
+			return
+		}
+		measuring = &mark
+	}
+	if measuring != nil {
+		//the last step has no next one to wait through, no more jobs are sent while its confirmations arrive
+		setTarget(0)
+		jr.waitConfirmed(jr.Saturation.StepDuration)
+		jr.measureStep(*measuring)
+		fmt.Println("saturation search is over")
+	}
+	fmt.Println("all stages are over")
+	jr.Stop()
+}
+
+//waitConfirmed wait until no tx is unconfirmed, at most timeout
+func (jr *JobRunner) waitConfirmed(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for jr.Unconfirmed() > 0 && time.Now().Before(deadline) {
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-jr.StopChan:
+			return
+		}
+	}
+}
+
+func (jr *JobRunner) markStage(i int, start time.Time) {
+	jr.stageLock.Lock()
+	defer jr.stageLock.Unlock()
+	jr.StageMarks = append(jr.StageMarks, StageMark{Stage: jr.Stages[i], Start: start})
+	atomic.StoreInt32(&jr.stage, int32(i))
+}
+
+//endStage set the end of the running stage and return its mark
+func (jr *JobRunner) endStage(end time.Time) StageMark {
+	jr.stageLock.Lock()
+	defer jr.stageLock.Unlock()
+	n := len(jr.StageMarks)
+	if n == 0 {
+		return StageMark{}
+	}
+	if jr.StageMarks[n-1].End.IsZero() {
+		jr.StageMarks[n-1].End = end
+	}
+	return jr.StageMarks[n-1]
+}
+
+//currentStage return the name of the running stage, empty when the run has no stages
+func (jr *JobRunner) currentStage() string {
+	if len(jr.Stages) == 0 {
+		return ""
+	}
+	return jr.Stages[atomic.LoadInt32(&jr.stage)].Name
+}
+
+//tokenPool is a semaphore whose size can change while jobs hold tokens.
+//Every token is a worker id from 0 to capacity-1, no two holders have the same one.
+type tokenPool struct {
+	ticks chan int
+	lock  sync.Mutex
+	limit int
+	//tokens to withhold when they are released, because the limit went down while they were held
+	debt int
+	//spare are the ids of the withheld tokens, the lowest is handed out first when the limit goes up
+	spare []int
+}
+
+func newTokenPool(capacity int, limit int) *tokenPool {
+	p := &tokenPool{ticks: make(chan int, capacity)}
+	for id := capacity - 1; id >= 0; id-- {
+		p.spare = append(p.spare, id)
+	}
+	p.setLimit(limit)
+	return p
+}
+
+//acquire take a token and return its worker id, false if stop was closed first
+func (p *tokenPool) acquire(stop <-chan struct{}) (int, bool) {
+	select {
+	case id := <-p.ticks:
+		return id, true
+	case <-stop:
+		return 0, false
+	}
+}
+
+func (p *tokenPool) release(id int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.debt > 0 {
+		p.debt--
+		p.withhold(id)
+		return
+	}
+	p.ticks <- id
+}
+
+//withhold keep the id spare, sorted from the highest to the lowest
+func (p *tokenPool) withhold(id int) {
+	i := len(p.spare)
+	p.spare = append(p.spare, id)
+	for ; i > 0 && p.spare[i-1] < id; i-- {
+		p.spare[i] = p.spare[i-1]
+	}
+	p.spare[i] = id
+}
+
+//setLimit resize the pool, it never blocks
+func (p *tokenPool) setLimit(limit int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if limit > cap(p.ticks) {
+		limit = cap(p.ticks)
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	delta := limit - p.limit
+	p.limit = limit
+	for ; delta > 0; delta-- {
+		if p.debt > 0 {
+			p.debt--
+			continue
+		}
+		last := len(p.spare) - 1
+		p.ticks <- p.spare[last]
+		p.spare = p.spare[:last]
+	}
+	for ; delta < 0; delta++ {
+		select {
+		case id := <-p.ticks:
+			p.withhold(id)
+		default:
+			p.debt++
+		}
+	}
+}
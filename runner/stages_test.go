@@ -0,0 +1,180 @@
+package runner
+
+import (
+	"sort"
+	"testing"
+)
+
+//poolOp is one call on a tokenPool: acquire a token, release the last acquired one, or set the limit to n
+type poolOp struct {
+	op string
+	n  int
+}
+
+func TestTokenPool(t *testing.T) {
+	tests := []struct {
+		name            string
+		capacity, limit int
+		ops             []poolOp
+		available, debt int
+		//ids are the worker ids handed out by the acquires, in order
+		ids []int
+	}{
+		{
+			name:      "initial limit",
+			capacity:  4,
+			limit:     2,
+			ops:       []poolOp{{op: "acquire"}},
+			available: 1,
+			ids:       []int{0},
+		},
+		{
+			name:      "limit above capacity",
+			capacity:  2,
+			limit:     2,
+			ops:       []poolOp{{op: "limit", n: 5}},
+			available: 2,
+		},
+		{
+			name:     "negative limit",
+			capacity: 2,
+			limit:    2,
+			ops:      []poolOp{{op: "limit", n: -1}},
+		},
+		{
+			name:     "lower with free tokens",
+			capacity: 4,
+			limit:    4,
+			ops:      []poolOp{{op: "limit", n: 1}, {op: "acquire"}},
+			ids:      []int{3},
+		},
+		{
+			name:     "lower while held",
+			capacity: 4,
+			limit:    4,
+			ops:      []poolOp{{op: "acquire"}, {op: "acquire"}, {op: "acquire"}, {op: "limit", n: 1}},
+			debt:     2,
+			ids:      []int{0, 1, 2},
+		},
+		{
+			name:     "released tokens pay the debt",
+			capacity: 4,
+			limit:    4,
+			ops: []poolOp{
+				{op: "acquire"}, {op: "acquire"}, {op: "acquire"}, {op: "limit", n: 1},
+				{op: "release"}, {op: "release"},
+			},
+			ids: []int{0, 1, 2},
+		},
+		{
+			name:     "release after the debt",
+			capacity: 4,
+			limit:    4,
+			ops: []poolOp{
+				{op: "acquire"}, {op: "acquire"}, {op: "acquire"}, {op: "limit", n: 1},
+				{op: "release"}, {op: "release"}, {op: "release"}, {op: "acquire"},
+			},
+			ids: []int{0, 1, 2, 0},
+		},
+		{
+			name:     "raise cancels the debt first",
+			capacity: 4,
+			limit:    4,
+			ops: []poolOp{
+				{op: "acquire"}, {op: "acquire"}, {op: "acquire"}, {op: "acquire"},
+				{op: "limit", n: 2}, {op: "limit", n: 3},
+			},
+			debt: 1,
+			ids:  []int{0, 1, 2, 3},
+		},
+		{
+			name:     "raise hands out the lowest spare id",
+			capacity: 4,
+			limit:    4,
+			ops: []poolOp{
+				{op: "limit", n: 1}, {op: "limit", n: 3},
+				{op: "acquire"}, {op: "acquire"}, {op: "acquire"},
+			},
+			ids: []int{3, 0, 1},
+		},
+		{
+			name:     "withheld ids come back in order",
+			capacity: 4,
+			limit:    4,
+			ops: []poolOp{
+				{op: "acquire"}, {op: "acquire"}, {op: "limit", n: 0},
+				{op: "release"}, {op: "release"}, {op: "limit", n: 1}, {op: "acquire"},
+			},
+			ids: []int{0, 1, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTokenPool(tt.capacity, tt.limit)
+			var held, ids []int
+			for i, op := range tt.ops {
+				switch op.op {
+				case "acquire":
+					if len(p.ticks) == 0 {
+						t.Fatalf("op %d: no token to acquire", i)
+					}
+					id, ok := p.acquire(nil)
+					if !ok {
+						t.Fatalf("op %d: acquire failed", i)
+					}
+					held = append(held, id)
+					ids = append(ids, id)
+				case "release":
+					p.release(held[len(held)-1])
+					held = held[:len(held)-1]
+				case "limit":
+					p.setLimit(op.n)
+				}
+				checkPool(t, p, held)
+			}
+			if len(p.ticks) != tt.available {
+				t.Errorf("%d tokens available, want %d", len(p.ticks), tt.available)
+			}
+			if p.debt != tt.debt {
+				t.Errorf("debt is %d, want %d", p.debt, tt.debt)
+			}
+			if len(ids) != len(tt.ids) {
+				t.Fatalf("acquired ids %v, want %v", ids, tt.ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.ids[i] {
+					t.Fatalf("acquired ids %v, want %v", ids, tt.ids)
+				}
+			}
+		})
+	}
+}
+
+//checkPool check that the tokens in use match the limit and that every worker id is
+//either available, held or spare exactly once
+func checkPool(t *testing.T, p *tokenPool, held []int) {
+	t.Helper()
+	available := make([]int, 0, len(p.ticks))
+	for len(p.ticks) > 0 {
+		available = append(available, <-p.ticks)
+	}
+	for _, id := range available {
+		p.ticks <- id
+	}
+	if n := len(available) + len(held) - p.debt; n != p.limit {
+		t.Fatalf("%d available + %d held - %d debt = %d tokens, want the limit %d", len(available), len(held), p.debt, n, p.limit)
+	}
+	if !sort.SliceIsSorted(p.spare, func(i, j int) bool { return p.spare[i] > p.spare[j] }) {
+		t.Fatalf("spare ids %v are not sorted from the highest", p.spare)
+	}
+	ids := append(append(append([]int(nil), available...), held...), p.spare...)
+	sort.Ints(ids)
+	if len(ids) != cap(p.ticks) {
+		t.Fatalf("ids %v, want every id below %d once", ids, cap(p.ticks))
+	}
+	for i, id := range ids {
+		if id != i {
+			t.Fatalf("ids %v, want every id below %d once", ids, cap(p.ticks))
+		}
+	}
+}
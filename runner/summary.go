@@ -0,0 +1,459 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/shimron/stressingtool/job"
+	"github.com/shimron/stressingtool/metrics"
+)
+
+//jobSummary accumulate the outcome of a set of jobs
+type jobSummary struct {
+	jobCount      int
+	successCount  int
+	failedCount   int
+	finishedCount int
+	retryCount    int
+	retriedCount  int
+	execution     *metrics.Histogram
+	confirm       *metrics.Histogram
+	//save 10 failed job name ( only used to  validate  transactions were failed exactly )
+	failedJobs []string
+}
+
+func newJobSummary() *jobSummary {
+	return &jobSummary{
+		execution:  metrics.NewHistogram(),
+		confirm:    metrics.NewHistogram(),
+		failedJobs: make([]string, 0, 10),
+	}
+}
+
+//add account one job, txStat is the stat received from the block listener, nil if no event was received
+func (s *jobSummary) add(jb *job.JobStat, txStat *job.JobStat) {
+	s.jobCount++
+	if jb.Retries > 0 {
+		s.retryCount += jb.Retries
+		s.retriedCount++
+	}
+	if cost := jb.ExecutedTime.Sub(jb.SubmitTime); cost > 0 {
+		s.execution.Record(cost)
+	}
+
+	if len(jb.TXID) == 0 {
+		s.finishedCount++
+		//a job without txid failed when the call itself returned an error
+		if jb.ErrorMsg != "" {
+			s.fail(jb)
+			return
+		}
+		s.successCount++
+		return
+	}
+	//未找到对应的txid对应的job stat，认为任务失败
+	if txStat == nil {
+		s.fail(jb)
+		return
+	}
+
+	s.finishedCount++
+	//收到tx的block event认定为成功，收到rejection event认定为失败
+	if !txStat.IsSuccess {
+		s.fail(jb)
+		return
+	}
+	s.successCount++
+	//仅计算写入ledger的交易确认时间
+	if cost := txStat.TXConfirmedTime.Sub(jb.ExecutedTime); cost > 0 {
+		s.confirm.Record(cost)
+	}
+}
+
+func (s *jobSummary) fail(jb *job.JobStat) {
+	s.failedCount++
+	if len(s.failedJobs) < cap(s.failedJobs) {
+		s.failedJobs = append(s.failedJobs, jb.Name)
+	}
+}
+
+//Summary is the outcome of a run, latencies and durations are in seconds
+type Summary struct {
+	Name      string    `json:"name"`
+	StartTime time.Time `json:"start_time"`
+	StopTime  time.Time `json:"stop_time"`
+	EndTime   time.Time `json:"end_time"`
+	//TotalTime is from the start to the end of the run, ExecutionTime from the start to the last job done
+	TotalTime     float64 `json:"total_time"`
+	ExecutionTime float64 `json:"execution_time"`
+	//Throughput is successful jobs per second of execution time
+	Throughput float64 `json:"throughput"`
+	Total      *Group  `json:"total"`
+	//breakdowns of the jobs, a job is counted in the group of each of its tags
+	Operations map[string]*Group `json:"operations,omitempty"`
+	Functions  map[string]*Group `json:"functions,omitempty"`
+	Chaincodes map[string]*Group `json:"chaincodes,omitempty"`
+	Tags       map[string]*Group `json:"tags,omitempty"`
+	Identities map[string]*Group `json:"identities,omitempty"`
+	Stages     []*StageSummary   `json:"stages,omitempty"`
+	OpenLoop   *OpenLoopSummary  `json:"open_loop,omitempty"`
+	Failures   *FailureSummary   `json:"failures,omitempty"`
+	Blocks     *BlockSummary     `json:"blocks,omitempty"`
+	Saturation *Saturation       `json:"saturation,omitempty"`
+}
+
+//Group is the outcome of a set of jobs
+type Group struct {
+	JobCount      int     `json:"job_count"`
+	FinishedCount int     `json:"finished_count"`
+	SuccessCount  int     `json:"success_count"`
+	FailedCount   int     `json:"failed_count"`
+	SuccessRate   float64 `json:"success_rate"` //percent
+	//RetryCount is the number of retried calls, RetriedCount the number of jobs with at least one retry
+	RetryCount   int     `json:"retry_count"`
+	RetriedCount int     `json:"retried_count"`
+	Execution    Latency `json:"execution"`
+	Confirm      Latency `json:"confirm"`
+	//FailedJobs are the names of the first 10 failed jobs
+	FailedJobs []string `json:"failed_jobs,omitempty"`
+}
+
+//Latency summarize a latency histogram, in seconds
+type Latency struct {
+	Count int64   `json:"count"`
+	Min   float64 `json:"min"`
+	Mean  float64 `json:"mean"`
+	Max   float64 `json:"max"`
+	//Percentiles by name, e.g. p99.9
+	Percentiles map[string]float64 `json:"percentiles"`
+	//Buckets is the distribution over metrics.DefaultBuckets, only for the total
+	Buckets []LatencyBucket `json:"buckets,omitempty"`
+}
+
+//LatencyBucket is the count of latencies within the upper bound and above the previous one
+type LatencyBucket struct {
+	UpperBound float64 `json:"upper_bound"` //seconds, 0 for +Inf
+	Count      int64   `json:"count"`
+}
+
+func newLatencyBuckets(h *metrics.Histogram) []LatencyBucket {
+	buckets := make([]LatencyBucket, 0, len(metrics.DefaultBuckets)+1)
+	for _, b := range h.Buckets(metrics.DefaultBuckets) {
+		buckets = append(buckets, LatencyBucket{UpperBound: b.UpperBound.Seconds(), Count: b.Count})
+	}
+	return buckets
+}
+
+//StageSummary is the outcome of one stage of a load profile
+type StageSummary struct {
+	Name string `json:"name"`
+	//Start and End are relative to the start of the run
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	From       float64 `json:"from"`
+	To         float64 `json:"to"`
+	Throughput float64 `json:"throughput"` //jobs per second
+	*Group
+}
+
+//OpenLoopSummary is what the open loop could not send on time
+type OpenLoopSummary struct {
+	//TargetRate is the rate of the run, 0 when stages drove it
+	TargetRate   float64 `json:"target_rate"`
+	DroppedCount int64   `json:"dropped_count"`
+	DelayedCount int64   `json:"delayed_count"`
+	AvgDelay     float64 `json:"avg_delay"`
+	MaxDelay     float64 `json:"max_delay"`
+}
+
+//percentileName name a percentile the way the summary prints it, e.g. p99.9
+func percentileName(p float64) string {
+	return fmt.Sprintf("p%v", p)
+}
+
+func newLatency(h *metrics.Histogram) Latency {
+	l := Latency{
+		Count:       h.Count(),
+		Min:         h.Min().Seconds(),
+		Mean:        h.Mean().Seconds(),
+		Max:         h.Max().Seconds(),
+		Percentiles: make(map[string]float64, len(percentiles)),
+	}
+	for _, p := range percentiles {
+		l.Percentiles[percentileName(p)] = h.Percentile(p).Seconds()
+	}
+	return l
+}
+
+func (s *jobSummary) group() *Group {
+	g := &Group{
+		JobCount:      s.jobCount,
+		FinishedCount: s.finishedCount,
+		SuccessCount:  s.successCount,
+		FailedCount:   s.failedCount,
+		RetryCount:    s.retryCount,
+		RetriedCount:  s.retriedCount,
+		Execution:     newLatency(s.execution),
+		Confirm:       newLatency(s.confirm),
+		FailedJobs:    s.failedJobs,
+	}
+	if s.jobCount > 0 {
+		g.SuccessRate = float64(s.successCount) * 100 / float64(s.jobCount)
+	}
+	return g
+}
+
+//Summary caculate the summary of the run
+func (jr *JobRunner) Summary() *Summary {
+	if jr.EndTime.IsZero() {
+		jr.EndTime = time.Now()
+	}
+
+	total := newJobSummary()
+	operations := make(breakdown)
+	functions := make(breakdown)
+	chaincodes := make(breakdown)
+	tags := make(breakdown)
+	identities := make(breakdown)
+	stages := make(breakdown)
+	jr.States.Lock.RLock()
+	for _, jb := range jr.States.JobStats {
+		txStat := jr.TxStats.Get(jb.TXID)
+		total.add(jb, txStat)
+		operations.add(jb.Operation, jb, txStat)
+		functions.add(jb.Function, jb, txStat)
+		chaincodes.add(jb.ChaincodeID, jb, txStat)
+		identities.add(jb.Identity, jb, txStat)
+		stages.add(jb.Stage, jb, txStat)
+		for _, tag := range jb.Tags {
+			tags.add(tag, jb, txStat)
+		}
+	}
+	jr.States.Lock.RUnlock()
+
+	s := &Summary{
+		Name:          jr.Name,
+		StartTime:     jr.StartTime,
+		StopTime:      jr.StopTime,
+		EndTime:       jr.EndTime,
+		TotalTime:     jr.EndTime.Sub(jr.StartTime).Seconds(),
+		ExecutionTime: jr.StopTime.Sub(jr.StartTime).Seconds(),
+		Total:         total.group(),
+		Failures:      summarizeFailures(jr.Failures()),
+		Blocks:        jr.blockSummary(),
+		Saturation:    jr.Saturation,
+	}
+	if s.ExecutionTime > 0 {
+		s.Throughput = float64(total.successCount) / s.ExecutionTime
+	}
+	s.Total.Execution.Buckets = newLatencyBuckets(total.execution)
+	s.Total.Confirm.Buckets = newLatencyBuckets(total.confirm)
+
+	if jr.OpenLoopStats.Ran || jr.Rate > 0 {
+		ols := jr.OpenLoopStats
+		s.OpenLoop = &OpenLoopSummary{
+			TargetRate:   jr.Rate,
+			DroppedCount: ols.Dropped,
+			DelayedCount: ols.Delayed,
+			MaxDelay:     time.Duration(ols.DelayMax).Seconds(),
+		}
+		if ols.Delayed > 0 {
+			s.OpenLoop.AvgDelay = time.Duration(ols.DelayTotal / ols.Delayed).Seconds()
+		}
+	}
+
+	for _, mark := range jr.StageMarks {
+		st, ok := stages[mark.Name]
+		if !ok {
+			st = newJobSummary()
+		}
+		end := mark.End
+		if end.IsZero() {
+			end = jr.StopTime
+		}
+		ss := &StageSummary{
+			Name:  mark.Name,
+			Start: mark.Start.Sub(jr.StartTime).Seconds(),
+			End:   end.Sub(jr.StartTime).Seconds(),
+			From:  mark.From,
+			To:    mark.To,
+			Group: st.group(),
+		}
+		if duration := ss.End - ss.Start; duration > 0 {
+			ss.Throughput = float64(st.jobCount) / duration
+		}
+		s.Stages = append(s.Stages, ss)
+	}
+
+	//a breakdown is only useful when the run mixed several of them
+	if len(operations) > 1 {
+		s.Operations = operations.groups()
+	}
+	if len(functions) > 1 {
+		s.Functions = functions.groups()
+	}
+	if len(chaincodes) > 1 {
+		s.Chaincodes = chaincodes.groups()
+	}
+	if len(tags) > 0 {
+		s.Tags = tags.groups()
+	}
+	if len(identities) > 1 {
+		s.Identities = identities.groups()
+	}
+	return s
+}
+
+//CollectStates caculate summary info, print it and return it
+func (jr *JobRunner) CollectStates() *Summary {
+	s := jr.Summary()
+	s.Print()
+	return s
+}
+
+//Save write the summary to a json file
+func (s *Summary) Save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+//Print print the summary in the text format of the tool
+func (s *Summary) Print() {
+	total := s.Total
+	fmt.Println("********Summary*******")
+	fmt.Printf("total job count:%d\n", total.JobCount)
+	fmt.Printf("total time cost:%fs\n", s.TotalTime)
+	fmt.Printf("total job execution time cost:%fs\n", s.ExecutionTime)
+	fmt.Printf("throughput:%.2f successful jobs/s\n", s.Throughput)
+	fmt.Printf("finished job count:%d\n", total.FinishedCount)
+	fmt.Printf("successful job count:%d\n", total.SuccessCount)
+	fmt.Printf("failed job count:%d\n", total.FailedCount)
+	if total.RetryCount > 0 {
+		fmt.Printf("retry count:%d in %d jobs\n", total.RetryCount, total.RetriedCount)
+	}
+	fmt.Printf("min execution cost:%fs\n", total.Execution.Min)
+	fmt.Printf("max execution cost:%fs\n", total.Execution.Max)
+	fmt.Printf("avg execution cost:%fs\n", total.Execution.Mean)
+	fmt.Printf("min confirm cost:%fs\n", total.Confirm.Min)
+	fmt.Printf("max confirm cost:%fs\n", total.Confirm.Max)
+	fmt.Printf("avg confirm cost:%fs\n", total.Confirm.Mean)
+	printPercentiles("execution cost", total.Execution)
+	printPercentiles("confirm cost", total.Confirm)
+	fmt.Printf("first 10 failed job names:%v\n", total.FailedJobs)
+	if s.Failures != nil {
+		s.Failures.print()
+	}
+	if ols := s.OpenLoop; ols != nil {
+		if ols.TargetRate > 0 {
+			fmt.Printf("target rate:%.2f jobs/s\n", ols.TargetRate)
+		} else {
+			fmt.Println("target rate:following the stages")
+		}
+		fmt.Printf("dropped job count:%d\n", ols.DroppedCount)
+		fmt.Printf("delayed job count:%d\n", ols.DelayedCount)
+		fmt.Printf("avg delay:%fs\n", ols.AvgDelay)
+		fmt.Printf("max delay:%fs\n", ols.MaxDelay)
+	}
+
+	if s.Blocks != nil {
+		s.Blocks.print()
+	}
+
+	if len(s.Stages) > 0 {
+		fmt.Println("********Stages*******")
+		for _, st := range s.Stages {
+			fmt.Printf("%s: %.1fs-%.1fs target:%.2f->%.2f job count:%d successful:%d failed:%d throughput:%.2f jobs/s avg execution cost:%fs max execution cost:%fs avg confirm cost:%fs max confirm cost:%fs\n",
+				st.Name, st.Start, st.End, st.From, st.To,
+				st.JobCount, st.SuccessCount, st.FailedCount, st.Throughput,
+				st.Execution.Mean, st.Execution.Max, st.Confirm.Mean, st.Confirm.Max)
+		}
+	}
+
+	if s.Saturation != nil {
+		s.Saturation.print()
+	}
+
+	printGroups("Operations", s.Operations, total.JobCount)
+	printGroups("Functions", s.Functions, total.JobCount)
+	printGroups("Chaincodes", s.Chaincodes, total.JobCount)
+	printGroups("Tags", s.Tags, total.JobCount)
+	printGroups("Identities", s.Identities, total.JobCount)
+}
+
+//breakdown split the jobs into groups sharing a key, e.g. an operation or a tag
+type breakdown map[string]*jobSummary
+
+func (b breakdown) add(key string, jb *job.JobStat, txStat *job.JobStat) {
+	s, ok := b[key]
+	if !ok {
+		s = newJobSummary()
+		b[key] = s
+	}
+	s.add(jb, txStat)
+}
+
+func (b breakdown) groups() map[string]*Group {
+	groups := make(map[string]*Group, len(b))
+	for key, s := range b {
+		groups[key] = s.group()
+	}
+	return groups
+}
+
+//printGroups print one line per group, sorted by key
+func printGroups(title string, groups map[string]*Group, totalCount int) {
+	if len(groups) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fmt.Printf("********%s*******\n", title)
+	for _, key := range keys {
+		g := groups[key]
+		name := key
+		if name == "" {
+			name = "(none)"
+		}
+		fmt.Printf("%s: job count:%d (%.1f%%) successful:%d failed:%d success rate:%.2f%% retries:%d execution cost p50:%fs p90:%fs p99:%fs confirm cost p50:%fs p90:%fs p99:%fs\n",
+			name, g.JobCount, float64(g.JobCount)*100/float64(totalCount), g.SuccessCount, g.FailedCount, g.SuccessRate, g.RetryCount,
+			g.Execution.Percentiles["p50"], g.Execution.Percentiles["p90"], g.Execution.Percentiles["p99"],
+			g.Confirm.Percentiles["p50"], g.Confirm.Percentiles["p90"], g.Confirm.Percentiles["p99"])
+	}
+}
+
+//percentiles reported for every latency
+var percentiles = []float64{50, 90, 95, 99, 99.9}
+
+//printPercentiles print the percentiles and the bucketed distribution of a latency
+func printPercentiles(name string, l Latency) {
+	fmt.Printf("%s percentiles:", name)
+	for _, p := range percentiles {
+		fmt.Printf(" %s:%fs", percentileName(p), l.Percentiles[percentileName(p)])
+	}
+	fmt.Printf(" max:%fs\n", l.Max)
+
+	if l.Count == 0 {
+		return
+	}
+	fmt.Printf("%s distribution:\n", name)
+	for _, b := range l.Buckets {
+		if b.Count == 0 {
+			continue
+		}
+		bound := "+Inf"
+		if b.UpperBound > 0 {
+			bound = time.Duration(math.Round(b.UpperBound * float64(time.Second))).String()
+		}
+		fmt.Printf("  <= %-6s %d (%.2f%%)\n", bound, b.Count, float64(b.Count)*100/float64(l.Count))
+	}
+}
@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shimron/stressingtool/metrics"
+)
+
+//Timeline rebuild what happened in every interval of the run from the job stats
+func (jr *JobRunner) Timeline(interval time.Duration) *metrics.Timeline {
+	jr.States.Lock.RLock()
+	events := make([]metrics.Event, 0, 3*len(jr.States.JobStats))
+	for _, js := range jr.States.JobStats {
+		events = append(events, metrics.Event{At: js.SubmitTime, Kind: metrics.Submitted})
+
+		//a job that got a txid was executed, a job without txid and with an error was not
+		if js.TXID == "" && js.ErrorMsg != "" {
+			events = append(events, metrics.Event{At: js.ExecutedTime, Kind: metrics.Errored})
+			continue
+		}
+		events = append(events, metrics.Event{
+			At:      js.ExecutedTime,
+			Kind:    metrics.Executed,
+			Latency: js.ExecutedTime.Sub(js.SubmitTime),
+			Pending: js.TXID != "",
+		})
+
+		txStat := jr.TxStats.Get(js.TXID)
+		switch {
+		case js.TXID == "" || txStat == nil:
+		case txStat.IsSuccess:
+			events = append(events, metrics.Event{
+				At:      txStat.TXConfirmedTime,
+				Kind:    metrics.Confirmed,
+				Latency: txStat.TXConfirmedTime.Sub(js.ExecutedTime),
+			})
+		default:
+			events = append(events, metrics.Event{At: txStat.TXConfirmedTime, Kind: metrics.Rejected})
+		}
+	}
+	jr.States.Lock.RUnlock()
+
+	return metrics.BuildTimeline(jr.StartTime, interval, events)
+}
+
+//SaveTimeline write the timeline of the run to a .json or .csv file
+func (jr *JobRunner) SaveTimeline(path string, interval time.Duration) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tl := jr.Timeline(interval)
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		return tl.WriteJSON(f)
+	}
+	return tl.WriteCSV(f)
+}
@@ -0,0 +1,124 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/shimron/stressingtool/chaincode"
+)
+
+const defaultReadyTimeout = 2 * time.Minute
+
+//DeployConfig deploy the chaincode before the run, the chaincode id it returns replaces chaincode_id
+type DeployConfig struct {
+	//Path is the chaincode path, Name the chaincode name of a peer in dev mode
+	Path string `yaml:"path" json:"path"`
+	Name string `yaml:"name" json:"name"`
+	//Function and Args are the constructor, init by default
+	Function string   `yaml:"function" json:"function"`
+	Args     []string `yaml:"args" json:"args"`
+	//Fresh append a unique arg to the constructor, so every run gets a new chaincode id and an empty state
+	Fresh bool `yaml:"fresh" json:"fresh"`
+	//Ready is the test query that must succeed before the load starts
+	Ready ReadyQuery `yaml:"ready" json:"ready"`
+	//ReadyTimeout is how long the test query is retried, 2m by default
+	ReadyTimeout string `yaml:"ready_timeout" json:"ready_timeout"`
+}
+
+//ReadyQuery is a query the deployed chaincode answers once it is up
+type ReadyQuery struct {
+	Function string   `yaml:"function" json:"function"`
+	Args     []string `yaml:"args" json:"args"`
+}
+
+func (cfg *DeployConfig) validate() error {
+	if (cfg.Path == "") == (cfg.Name == "") {
+		return errors.New("deploy needs one of path and name")
+	}
+	if cfg.Fresh && cfg.Path == "" {
+		return errors.New("deploy fresh needs path, the name of a chaincode in dev mode is fixed")
+	}
+	if cfg.Ready.Function == "" {
+		return errors.New("deploy ready function is required")
+	}
+	if _, err := cfg.readyTimeout(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cfg *DeployConfig) readyTimeout() (time.Duration, error) {
+	if cfg.ReadyTimeout == "" {
+		return defaultReadyTimeout, nil
+	}
+	d, err := time.ParseDuration(cfg.ReadyTimeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid deploy ready_timeout %q", cfg.ReadyTimeout)
+	}
+	return d, nil
+}
+
+//DeployChaincode deploy the chaincode of the deploy section, wait until its test query succeeds and use its chaincode id for the run
+func (sc *Scenario) DeployChaincode() error {
+	cfg := sc.Deploy
+	if cfg == nil {
+		return nil
+	}
+	deployer, ok := sc.transport.(chaincode.Deployer)
+	if !ok {
+		return fmt.Errorf("transport %s can not deploy", sc.Transport)
+	}
+	function := cfg.Function
+	if function == "" {
+		function = "init"
+	}
+	args := append([]string{function}, cfg.Args...)
+	if cfg.Fresh {
+		args = append(args, strconv.FormatInt(time.Now().UnixNano(), 10))
+	}
+
+	//on a network with security the first identity deploys
+	var secureContext string
+	if len(sc.Identities) > 0 {
+		secureContext = sc.Identities[0].EnrollID
+	}
+	start := time.Now()
+	ccid, err := deployer.Deploy(sc.target(), chaincode.DeploySpec{Path: cfg.Path, Name: cfg.Name, Args: args, SecureContext: secureContext})
+	if err != nil {
+		return fmt.Errorf("fail to deploy:%v", err)
+	}
+	fmt.Printf("deployed chaincode %s\n", ccid)
+
+	timeout, _ := cfg.readyTimeout()
+	query := append([]string{cfg.Ready.Function}, cfg.Ready.Args...)
+	for {
+		_, err := sc.transport.QueryOrInvoke(sc.target(), ccid, query, false, secureContext)
+		if err == nil {
+			break
+		}
+		if time.Since(start) > timeout {
+			return fmt.Errorf("chaincode %s is not ready after %v:%v", ccid, timeout, err)
+		}
+		fmt.Printf("waiting for chaincode to be ready:%v\n", err)
+		time.Sleep(time.Second)
+	}
+	fmt.Printf("chaincode is ready after %v\n", time.Since(start).Truncate(time.Millisecond))
+
+	sc.setChaincodeID(ccid)
+	return nil
+}
+
+//setChaincodeID point the jobs at another chaincode: the scenario, its workflow and the mix operations without their own chaincode_id
+func (sc *Scenario) setChaincodeID(ccid string) {
+	sc.ChaincodeID = ccid
+	if sc.flow != nil {
+		sc.flow.CCID = ccid
+	}
+	for i := range sc.Mix {
+		if sc.Mix[i].inherited {
+			sc.Mix[i].ChaincodeID = ccid
+		}
+	}
+}
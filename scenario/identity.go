@@ -0,0 +1,135 @@
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+
+	"github.com/shimron/stressingtool/chaincode"
+)
+
+//identity modes
+const (
+	//IdentityRoundRobin give every job the next identity, the steps of a workflow share the identity of their first step
+	IdentityRoundRobin = "round_robin"
+	//IdentityVirtualUser give every worker of the runner one identity, all its jobs are sent as the same user
+	IdentityVirtualUser = "virtual_user"
+)
+
+//Identity is an enrolled user of a network with security.
+//The secret may refer to environment variables, e.g. ${USER1_SECRET}.
+type Identity struct {
+	EnrollID     string `yaml:"enroll_id" json:"enroll_id"`
+	EnrollSecret string `yaml:"enroll_secret" json:"enroll_secret"`
+}
+
+//MarshalJSON hide the secret, the scenario is saved with the results
+func (id Identity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		EnrollID string `json:"enroll_id"`
+	}{id.EnrollID})
+}
+
+func (sc *Scenario) validateIdentities() error {
+	switch sc.IdentityMode {
+	case "", IdentityRoundRobin, IdentityVirtualUser:
+	default:
+		return fmt.Errorf("unknown identity_mode %q, it must be round_robin or virtual_user", sc.IdentityMode)
+	}
+	seen := make(map[string]bool, len(sc.Identities))
+	for _, id := range sc.Identities {
+		if id.EnrollID == "" {
+			return errors.New("identity enroll_id is required")
+		}
+		if seen[id.EnrollID] {
+			return fmt.Errorf("identity %s is listed twice", id.EnrollID)
+		}
+		seen[id.EnrollID] = true
+	}
+	if sc.IdentityMode == IdentityVirtualUser && sc.workers() < len(sc.Identities) {
+		return fmt.Errorf("identity_mode virtual_user needs a worker per identity, %d identities for %d workers", len(sc.Identities), sc.workers())
+	}
+	if _, err := sc.registrar(); err != nil {
+		return err
+	}
+	return nil
+}
+
+//registrar return the login address of the transport: the REST /registrar endpoint or the devops service
+func (sc *Scenario) registrar() (string, error) {
+	if sc.Transport == chaincode.TransportDevops {
+		return sc.PeerAddr, nil
+	}
+	if sc.RegistrarURL != "" {
+		if _, err := url.ParseRequestURI(sc.RegistrarURL); err != nil {
+			return "", fmt.Errorf("invalid registrar_url:%v", err)
+		}
+		return sc.RegistrarURL, nil
+	}
+	u, err := url.Parse(sc.RestURL)
+	if err != nil {
+		return "", err
+	}
+	u.Path = "/registrar"
+	return u.String(), nil
+}
+
+//Login log every identity in, their enrollment ids are then the secureContext of the jobs
+func (sc *Scenario) Login() error {
+	if len(sc.Identities) == 0 {
+		return nil
+	}
+	registrar, ok := sc.transport.(chaincode.Registrar)
+	if !ok {
+		return fmt.Errorf("transport %s can not log in", sc.Transport)
+	}
+	addr, _ := sc.registrar()
+	for _, id := range sc.Identities {
+		if err := registrar.Login(addr, id.EnrollID, os.ExpandEnv(id.EnrollSecret)); err != nil {
+			return fmt.Errorf("fail to log %s in:%v", id.EnrollID, err)
+		}
+	}
+	fmt.Printf("%d identities were logged in\n", len(sc.Identities))
+	return nil
+}
+
+//identity return the secureContext of the seq-th job in round robin, empty without identities.
+//With virtual_user the runner sets it from the worker that runs the job, see workerIdentity.
+func (sc *Scenario) identity(seq int) string {
+	n := len(sc.Identities)
+	if n == 0 || sc.IdentityMode == IdentityVirtualUser {
+		return ""
+	}
+	return sc.Identities[(seq-sc.Offset-1)%n].EnrollID
+}
+
+//workerIdentity return the secureContext of the jobs of a worker with virtual_user
+func (sc *Scenario) workerIdentity(worker int) string {
+	return sc.Identities[worker%len(sc.Identities)].EnrollID
+}
+
+//workers return the number of worker ids of the runner: the concurrency of the closed loop,
+//the highest stage target when stages drive it, or the in-flight cap of the open loop
+func (sc *Scenario) workers() int {
+	if sc.Rate > 0 || (sc.Profile == ProfileRate && (len(sc.Stages) > 0 || sc.Saturation != nil)) {
+		if sc.MaxInFlight > 0 {
+			return sc.MaxInFlight
+		}
+		return sc.concurrency()
+	}
+	stages, _ := sc.stages()
+	if s, _ := sc.saturation(); s != nil {
+		stages = s.Stages()
+	}
+	if len(stages) == 0 {
+		return sc.concurrency()
+	}
+	var peak float64
+	for _, st := range stages {
+		peak = math.Max(peak, math.Max(st.From, st.To))
+	}
+	return int(math.Ceil(peak))
+}
@@ -0,0 +1,736 @@
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shimron/stressingtool/chaincode"
+	"github.com/shimron/stressingtool/generator"
+	"github.com/shimron/stressingtool/job"
+	"github.com/shimron/stressingtool/runner"
+	"github.com/shimron/stressingtool/slo"
+	"github.com/shimron/stressingtool/workflow"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+//Scenario describes one load test run
+type Scenario struct {
+	Name           string   `yaml:"name" json:"name"`
+	RestURL        string   `yaml:"rest_url" json:"rest_url"`
+	EventAddr      string   `yaml:"event_addr" json:"event_addr"`
+	ChaincodeID    string   `yaml:"chaincode_id" json:"chaincode_id"`
+	Function       string   `yaml:"function" json:"function"`
+	Args           []string `yaml:"args" json:"args"`
+	IsInvoke       bool     `yaml:"invoke" json:"invoke"`
+	JobCount       int      `yaml:"job_count" json:"job_count"`
+	Offset         int      `yaml:"offset" json:"offset"`
+	ConcurrencyNum int      `yaml:"concurrency_num" json:"concurrency_num"`
+	//Rate switch to open loop at rate jobs per second, capped at max_in_flight jobs in flight
+	Rate             float64 `yaml:"rate" json:"rate"`
+	MaxInFlight      int     `yaml:"max_in_flight" json:"max_in_flight"`
+	DropOnSaturation bool    `yaml:"drop_on_saturation" json:"drop_on_saturation"`
+	//Profile is what the stages target: concurrency (default) or rate
+	Profile string `yaml:"profile" json:"profile"`
+	//Stages make a load profile, the run stops after the last stage
+	Stages []StageConfig `yaml:"stages" json:"stages"`
+	//Saturation raise the target step by step to find the peak sustainable throughput, it replaces stages
+	Saturation *SaturationConfig `yaml:"saturation" json:"saturation"`
+	//Duration stop the run after it, job_count may be 0 for an endless run
+	Duration string `yaml:"duration" json:"duration"`
+	//DrainTimeout is how long unconfirmed invokes are waited for after the last job, 60s by default
+	DrainTimeout string `yaml:"drain_timeout" json:"drain_timeout"`
+	//Deploy deploy the chaincode before the run, chaincode_id is not needed then
+	Deploy *DeployConfig `yaml:"deploy" json:"deploy"`
+	//Identities are logged in before the run and the jobs are spread across them, see IdentityMode
+	Identities   []Identity `yaml:"identities" json:"identities"`
+	IdentityMode string     `yaml:"identity_mode" json:"identity_mode"`
+	//RegistrarURL is the REST login endpoint, the /registrar path of rest_url by default
+	RegistrarURL string `yaml:"registrar_url" json:"registrar_url"`
+	//Transport is how jobs reach the peer: rest (default) through rest_url or devops through the grpc service at peer_addr
+	Transport string `yaml:"transport" json:"transport"`
+	PeerAddr  string `yaml:"peer_addr" json:"peer_addr"`
+	//HTTP tune the http client of rest_url: timeout, pool size, keep-alive, http2 and headers
+	HTTP chaincode.ClientConfig `yaml:"http" json:"http"`
+	//Devops tune the grpc client of the devops transport: timeout and tls
+	Devops chaincode.DevopsConfig `yaml:"devops" json:"devops"`
+	//Retry retry the calls that failed with a retryable class
+	Retry *RetryConfig `yaml:"retry" json:"retry"`
+	//MetricsAddr serve live prometheus metrics on http://<metrics_addr>/metrics during the run, e.g. :9100
+	MetricsAddr string `yaml:"metrics_addr" json:"metrics_addr"`
+	//SLO are the thresholds the run must meet, e.g. confirm.p99 max 3s
+	SLO []slo.Threshold `yaml:"slo" json:"slo"`
+	//Baseline compare the run to the summary of a previous one
+	Baseline *slo.Baseline `yaml:"baseline" json:"baseline"`
+	//Seed seeds every random value of the args templates, a time based seed is used when it is 0
+	Seed int64 `yaml:"seed" json:"seed"`
+	//Feeders bind template variables to the rows of data files
+	Feeders []generator.FeederConfig `yaml:"feeders" json:"feeders"`
+	//Workflow replace function and args with a chain of steps run by one virtual user per job
+	Workflow []workflow.StepConfig `yaml:"workflow" json:"workflow"`
+	//Mix replace function and args with weighted operations, every job draws one of them
+	Mix []Operation `yaml:"mix" json:"mix"`
+	//Tags are set on every job, the summary has a breakdown by tag
+	Tags []string `yaml:"tags" json:"tags"`
+
+	dir       string
+	transport chaincode.Transport
+	retry     *job.RetryPolicy
+	args      *generator.Template
+	feeders   []*generator.Feeder
+	flow      *workflow.Workflow
+	ops       *generator.Weighted
+	//bindLock guard the templates while the workers bind the jobs of a per_worker feeder
+	bindLock *sync.Mutex
+}
+
+//Operation is one entry of a weighted mix
+type Operation struct {
+	Name     string   `yaml:"name" json:"name"`
+	Weight   float64  `yaml:"weight" json:"weight"`
+	Function string   `yaml:"function" json:"function"`
+	Args     []string `yaml:"args" json:"args"`
+	IsInvoke bool     `yaml:"invoke" json:"invoke"`
+	//ChaincodeID override the chaincode_id of the scenario
+	ChaincodeID string `yaml:"chaincode_id" json:"chaincode_id"`
+	//Tags are added to the tags of the scenario for the jobs of the operation
+	Tags []string `yaml:"tags" json:"tags"`
+
+	args *generator.Template
+	//inherited is set when ChaincodeID is the one of the scenario
+	inherited bool
+}
+
+//SaturationConfig describes the steps of a saturation search, the target is the one of profile
+type SaturationConfig struct {
+	Start        float64 `yaml:"start" json:"start"`
+	Step         float64 `yaml:"step" json:"step"`
+	Max          float64 `yaml:"max" json:"max"`
+	StepDuration string  `yaml:"step_duration" json:"step_duration"`
+	//MinGain is the relative throughput gain below which the throughput is flat, 0.05 by default
+	MinGain          *float64 `yaml:"min_gain" json:"min_gain"`
+	MaxLatency       string   `yaml:"max_latency" json:"max_latency"`
+	MaxRejectionRate float64  `yaml:"max_rejection_rate" json:"max_rejection_rate"`
+}
+
+//RetryConfig is the retry policy of the calls, a retry waits backoff, then twice as long every time up to max_backoff
+type RetryConfig struct {
+	//MaxAttempts count the first call
+	MaxAttempts int `yaml:"max_attempts" json:"max_attempts"`
+	//Backoff is the delay before the first retry, 100ms by default
+	Backoff string `yaml:"backoff" json:"backoff"`
+	//MaxBackoff cap the delays, 5s by default
+	MaxBackoff string `yaml:"max_backoff" json:"max_backoff"`
+	//Jitter shorten every delay by a random part of up to jitter, 0.5 by default
+	Jitter *float64 `yaml:"jitter" json:"jitter"`
+	//On are the failure classes to retry, transport and http_status by default
+	On []string `yaml:"on" json:"on"`
+}
+
+//StageConfig is one stage of a load profile, the target moves linearly from from to to over duration.
+//From defaults to the to of the previous stage, so a constant stage only needs to.
+type StageConfig struct {
+	Name     string   `yaml:"name" json:"name"`
+	Duration string   `yaml:"duration" json:"duration"`
+	From     *float64 `yaml:"from" json:"from"`
+	To       float64  `yaml:"to" json:"to"`
+}
+
+//checkDuplicateKeys reject a yaml document with a key set twice in the same mapping,
+//the decoder would silently keep the last value
+func checkDuplicateKeys(b []byte) error {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	return duplicateKeys(doc, "")
+}
+
+func duplicateKeys(v interface{}, path string) error {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		seen := make(map[interface{}]bool, len(v))
+		for _, item := range v {
+			key := fmt.Sprint(item.Key)
+			if path != "" {
+				key = path + "." + key
+			}
+			if seen[item.Key] {
+				return fmt.Errorf("duplicate key %s", key)
+			}
+			seen[item.Key] = true
+			if err := duplicateKeys(item.Value, key); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			if err := duplicateKeys(e, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//Load read a scenario from a yaml or json file
+func Load(path string) (*Scenario, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sc := &Scenario{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(b, sc)
+	default:
+		if err = yaml.Unmarshal(b, sc); err == nil {
+			err = checkDuplicateKeys(b)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse scenario %s:%v", path, err)
+	}
+	if sc.Name == "" {
+		sc.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if sc.Seed == 0 {
+		sc.Seed = time.Now().UnixNano()
+	}
+	sc.dir = filepath.Dir(path)
+	if err := sc.Validate(); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+//Validate check the scenario and compile its args templates
+func (sc *Scenario) Validate() error {
+	switch sc.Transport {
+	case "", chaincode.TransportREST:
+		if sc.RestURL == "" {
+			return errors.New("rest_url is required")
+		}
+		if _, err := url.ParseRequestURI(sc.RestURL); err != nil {
+			return fmt.Errorf("invalid rest_url:%v", err)
+		}
+	case chaincode.TransportDevops:
+		if sc.PeerAddr == "" {
+			return errors.New("peer_addr is required by the devops transport")
+		}
+	default:
+		return fmt.Errorf("unknown transport %q, it must be rest or devops", sc.Transport)
+	}
+	if sc.EventAddr == "" {
+		return errors.New("event_addr is required")
+	}
+	if err := sc.validateIdentities(); err != nil {
+		return err
+	}
+	if sc.Deploy != nil {
+		if err := sc.Deploy.validate(); err != nil {
+			return err
+		}
+	} else if sc.ChaincodeID == "" {
+		return errors.New("chaincode_id is required")
+	}
+	kinds := 0
+	for _, set := range []bool{sc.Function != "", len(sc.Workflow) != 0, len(sc.Mix) != 0} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return errors.New("exactly one of function, workflow and mix is required")
+	}
+	if _, err := sc.RunDuration(); err != nil {
+		return err
+	}
+	if _, err := sc.DrainWait(); err != nil {
+		return err
+	}
+	var err error
+	if sc.transport, err = sc.newTransport(); err != nil {
+		return err
+	}
+	if sc.retry, err = sc.retryPolicy(); err != nil {
+		return err
+	}
+	if err := slo.Validate(sc.SLO); err != nil {
+		return err
+	}
+	if sc.Baseline != nil {
+		if err := sc.Baseline.Validate(); err != nil {
+			return err
+		}
+	}
+	if sc.JobCount < 0 {
+		return errors.New("job_count must not be negative")
+	}
+	if sc.JobCount == 0 && sc.Duration == "" && len(sc.Stages) == 0 && sc.Saturation == nil {
+		return errors.New("job_count is required unless duration, stages or saturation end the run")
+	}
+	if sc.ConcurrencyNum < 0 {
+		return errors.New("concurrency_num must not be negative")
+	}
+	if sc.Rate < 0 || sc.MaxInFlight < 0 {
+		return errors.New("rate and max_in_flight must not be negative")
+	}
+	if _, err := sc.stages(); err != nil {
+		return err
+	}
+	if _, err := sc.saturation(); err != nil {
+		return err
+	}
+
+	sc.feeders = make([]*generator.Feeder, 0, len(sc.Feeders))
+	sc.bindLock = new(sync.Mutex)
+	for i, cfg := range sc.Feeders {
+		f, err := generator.LoadFeeder(cfg, sc.dir, generator.SubSeed(sc.Seed, fmt.Sprintf("feeder/%d", i)))
+		if err != nil {
+			return err
+		}
+		if f.Mode == generator.ModePerWorker && f.OnEnd == generator.EndStop && f.Len() < sc.workers() {
+			return fmt.Errorf("feeder %s has %d rows, per_worker with on_end stop needs one row per worker, %d workers", cfg.File, f.Len(), sc.workers())
+		}
+		sc.feeders = append(sc.feeders, f)
+	}
+
+	//render the first job with a throwaway template and feeders so that template errors show up
+	//before any traffic is sent without consuming the random source of the run
+	t, err := generator.NewTemplate(generator.SubSeed(sc.Seed, "args"), sc.Args)
+	if err != nil {
+		return err
+	}
+	vars := make(map[string]string)
+	for i, f := range sc.feeders {
+		row, err := f.Clone(generator.SubSeed(sc.Seed, fmt.Sprintf("feeder/%d", i))).Next(0)
+		if err != nil {
+			return err
+		}
+		for k, v := range row {
+			if _, ok := vars[k]; ok {
+				return fmt.Errorf("variable %q is bound by more than one feeder", k)
+			}
+			vars[k] = v
+		}
+	}
+
+	if len(sc.Mix) != 0 {
+		return sc.compileMix(vars)
+	}
+
+	if len(sc.Workflow) != 0 {
+		wf, err := workflow.New(sc.Name, sc.target(), sc.ChaincodeID, sc.Workflow, generator.SubSeed(sc.Seed, "workflow"))
+		if err != nil {
+			return err
+		}
+		if err := wf.DryRun(sc.Offset+1, vars); err != nil {
+			return err
+		}
+		sc.flow, err = workflow.New(sc.Name, sc.target(), sc.ChaincodeID, sc.Workflow, generator.SubSeed(sc.Seed, "workflow"))
+		if err == nil {
+			sc.flow.Tags = sc.Tags
+			sc.flow.Transport = sc.transport
+			sc.flow.Retry = sc.retry
+		}
+		return err
+	}
+
+	if _, err := t.Render(generator.Data{Seq: sc.Offset + 1, Vars: vars}); err != nil {
+		return err
+	}
+	sc.args, err = generator.NewTemplate(generator.SubSeed(sc.Seed, "args"), sc.Args)
+	return err
+}
+
+//Job build the seq-th job, the first step of the workflow if the scenario has one.
+//Jobs must be built in order to be reproducible, generator.ErrExhausted is returned when a feeder ran out of rows.
+//With a per_worker feeder the job is a placeholder, its Bind renders it once the runner knows the worker that runs it.
+func (sc *Scenario) Job(seq int) (*job.Job, error) {
+	vars := make(map[string]string)
+	perWorker := false
+	for _, f := range sc.feeders {
+		if f.Mode == generator.ModePerWorker {
+			perWorker = true
+			continue
+		}
+		row, err := f.Next(0)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range row {
+			vars[k] = v
+		}
+	}
+	var op *Operation
+	if sc.ops != nil {
+		op = &sc.Mix[sc.ops.Pick()]
+	}
+	if !perWorker {
+		return sc.build(seq, op, vars)
+	}
+
+	name := fmt.Sprintf("%s_job_%d", sc.Name, seq)
+	if op != nil {
+		name += "_" + op.Name
+	}
+	jb := &job.Job{Name: name}
+	jb.Bind = func(worker int) (*job.Job, error) {
+		bound := make(map[string]string, len(vars))
+		for k, v := range vars {
+			bound[k] = v
+		}
+		for _, f := range sc.feeders {
+			if f.Mode != generator.ModePerWorker {
+				continue
+			}
+			row, err := f.Next(worker)
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range row {
+				bound[k] = v
+			}
+		}
+		sc.bindLock.Lock()
+		defer sc.bindLock.Unlock()
+		return sc.build(seq, op, bound)
+	}
+	return jb, nil
+}
+
+//build render the seq-th job with the variables of the feeders, op is the operation picked from the mix
+func (sc *Scenario) build(seq int, op *Operation, vars map[string]string) (*job.Job, error) {
+	if sc.flow != nil {
+		return sc.flow.Start(seq, sc.identity(seq), vars)
+	}
+	if op != nil {
+		rendered, err := op.args.Render(generator.Data{Seq: seq, Vars: vars})
+		if err != nil {
+			return nil, fmt.Errorf("operation %s:%v", op.Name, err)
+		}
+		jb := job.NewJob(fmt.Sprintf("%s_job_%d_%s", sc.Name, seq, op.Name), job.ChainCodeCommand{
+			URL:           sc.target(),
+			CCID:          op.ChaincodeID,
+			Args:          append([]string{op.Function}, rendered...),
+			IsInvoke:      op.IsInvoke,
+			SecureContext: sc.identity(seq),
+			Transport:     sc.transport,
+		})
+		jb.Operation = op.Name
+		jb.Tags = append(append([]string(nil), sc.Tags...), op.Tags...)
+		jb.Retry = sc.retry
+		return jb, nil
+	}
+
+	rendered, err := sc.args.Render(generator.Data{Seq: seq, Vars: vars})
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, len(rendered)+1)
+	args = append(args, sc.Function)
+	args = append(args, rendered...)
+	jb := job.NewJob(fmt.Sprintf("%s_job_%d", sc.Name, seq), job.ChainCodeCommand{
+		URL:           sc.target(),
+		CCID:          sc.ChaincodeID,
+		Args:          args,
+		IsInvoke:      sc.IsInvoke,
+		SecureContext: sc.identity(seq),
+		Transport:     sc.transport,
+	})
+	jb.Tags = sc.Tags
+	jb.Retry = sc.retry
+	return jb, nil
+}
+
+//compileMix check the operations of the mix and compile their args, every operation has its own random source
+func (sc *Scenario) compileMix(vars map[string]string) error {
+	weights := make([]float64, 0, len(sc.Mix))
+	names := make(map[string]bool)
+	for i := range sc.Mix {
+		op := &sc.Mix[i]
+		if op.Name == "" {
+			op.Name = op.Function
+		}
+		if names[op.Name] {
+			return fmt.Errorf("operation %q is defined twice", op.Name)
+		}
+		names[op.Name] = true
+		if op.Function == "" {
+			return fmt.Errorf("operation %s: function is required", op.Name)
+		}
+		if op.Weight <= 0 {
+			return fmt.Errorf("operation %s: weight must be positive", op.Name)
+		}
+		if op.ChaincodeID == "" || op.inherited {
+			op.ChaincodeID = sc.ChaincodeID
+			op.inherited = true
+		}
+		weights = append(weights, op.Weight)
+
+		seed := generator.SubSeed(sc.Seed, "operation/"+op.Name)
+		t, err := generator.NewTemplate(seed, op.Args)
+		if err != nil {
+			return fmt.Errorf("operation %s:%v", op.Name, err)
+		}
+		if _, err := t.Render(generator.Data{Seq: sc.Offset + 1, Vars: vars}); err != nil {
+			return fmt.Errorf("operation %s:%v", op.Name, err)
+		}
+		if op.args, err = generator.NewTemplate(seed, op.Args); err != nil {
+			return err
+		}
+	}
+	var err error
+	sc.ops, err = generator.NewWeighted(weights, generator.SubSeed(sc.Seed, "mix"))
+	return err
+}
+
+func (sc *Scenario) concurrency() int {
+	if sc.ConcurrencyNum <= 0 {
+		//same default as runner.NewJobRunner
+		return 10
+	}
+	return sc.ConcurrencyNum
+}
+
+//NewRunner create the JobRunner described by the scenario
+func (sc *Scenario) NewRunner() *runner.JobRunner {
+	jr := runner.NewJobRunner(sc.Name+"_runner", sc.ConcurrencyNum, sc.EventAddr)
+	jr.Rate = sc.Rate
+	jr.MaxInFlight = sc.MaxInFlight
+	jr.DropOnSaturation = sc.DropOnSaturation
+	jr.Stages, _ = sc.stages()
+	jr.StageRate = sc.Profile == ProfileRate
+	jr.Duration, _ = sc.RunDuration()
+	jr.Saturation, _ = sc.saturation()
+	jr.MetricsAddr = sc.MetricsAddr
+	if sc.IdentityMode == IdentityVirtualUser && len(sc.Identities) > 0 {
+		jr.Identity = sc.workerIdentity
+	}
+	//the baseline file is saved as an absolute path, report may run from another directory
+	saved := *sc
+	if sc.Baseline != nil && sc.Baseline.File != "" {
+		b := *sc.Baseline
+		if abs, err := filepath.Abs(sc.BaselineFile()); err == nil {
+			b.File = abs
+		}
+		saved.Baseline = &b
+	}
+	jr.Config, _ = json.Marshal(&saved)
+	return jr
+}
+
+//saturation convert the saturation config, nil if the scenario has none
+func (sc *Scenario) saturation() (*runner.Saturation, error) {
+	cfg := sc.Saturation
+	if cfg == nil {
+		return nil, nil
+	}
+	if len(sc.Stages) != 0 {
+		return nil, errors.New("saturation and stages must not be both set")
+	}
+	if cfg.Start <= 0 || cfg.Step <= 0 || cfg.Max < cfg.Start {
+		return nil, errors.New("saturation needs start > 0, step > 0 and max >= start")
+	}
+	s := &runner.Saturation{
+		Start:            cfg.Start,
+		Step:             cfg.Step,
+		Max:              cfg.Max,
+		MinGain:          0.05,
+		MaxRejectionRate: cfg.MaxRejectionRate,
+	}
+	if cfg.MinGain != nil {
+		s.MinGain = *cfg.MinGain
+	}
+	var err error
+	if s.StepDuration, err = time.ParseDuration(cfg.StepDuration); err != nil || s.StepDuration <= 0 {
+		return nil, fmt.Errorf("invalid saturation step_duration %q", cfg.StepDuration)
+	}
+	if cfg.MaxLatency != "" {
+		if s.MaxLatency, err = time.ParseDuration(cfg.MaxLatency); err != nil {
+			return nil, fmt.Errorf("invalid saturation max_latency %q", cfg.MaxLatency)
+		}
+	}
+	return s, nil
+}
+
+//newTransport create the client of the transport of the scenario
+func (sc *Scenario) newTransport() (chaincode.Transport, error) {
+	if sc.Transport == chaincode.TransportDevops {
+		return chaincode.NewDevopsClient(sc.Devops)
+	}
+	return chaincode.NewClient(sc.HTTP)
+}
+
+//Close release the connections of the transport
+func (sc *Scenario) Close() {
+	if c, ok := sc.transport.(interface {
+		Close()
+	}); ok {
+		c.Close()
+	}
+}
+
+//target return the address jobs are sent to with the transport of the scenario
+func (sc *Scenario) target() string {
+	if sc.Transport == chaincode.TransportDevops {
+		return sc.PeerAddr
+	}
+	return sc.RestURL
+}
+
+//retryPolicy convert the retry config, nil if the scenario has none
+func (sc *Scenario) retryPolicy() (*job.RetryPolicy, error) {
+	cfg := sc.Retry
+	if cfg == nil {
+		return nil, nil
+	}
+	backoff, maxBackoff := 100*time.Millisecond, 5*time.Second
+	var err error
+	if cfg.Backoff != "" {
+		if backoff, err = time.ParseDuration(cfg.Backoff); err != nil {
+			return nil, fmt.Errorf("invalid retry backoff %q", cfg.Backoff)
+		}
+	}
+	if cfg.MaxBackoff != "" {
+		if maxBackoff, err = time.ParseDuration(cfg.MaxBackoff); err != nil {
+			return nil, fmt.Errorf("invalid retry max_backoff %q", cfg.MaxBackoff)
+		}
+	}
+	jitter := 0.5
+	if cfg.Jitter != nil {
+		jitter = *cfg.Jitter
+	}
+	classes := cfg.On
+	if len(classes) == 0 {
+		classes = []string{job.ErrTransport, job.ErrHTTPStatus}
+	}
+	return job.NewRetryPolicy(cfg.MaxAttempts, backoff, maxBackoff, jitter, classes)
+}
+
+//RunDuration return the parsed duration, 0 if the run is not limited in time
+func (sc *Scenario) RunDuration() (time.Duration, error) {
+	if sc.Duration == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(sc.Duration)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", sc.Duration)
+	}
+	return d, nil
+}
+
+//SetDuration override the duration of the run, the rest of the scenario is already validated
+func (sc *Scenario) SetDuration(duration string) error {
+	old := sc.Duration
+	sc.Duration = duration
+	if _, err := sc.RunDuration(); err != nil {
+		sc.Duration = old
+		return err
+	}
+	return nil
+}
+
+//BaselineFile return the path of the baseline summary, relative to the scenario file, "" without baseline
+func (sc *Scenario) BaselineFile() string {
+	if sc.Baseline == nil || sc.Baseline.File == "" {
+		return ""
+	}
+	if filepath.IsAbs(sc.Baseline.File) {
+		return sc.Baseline.File
+	}
+	return filepath.Join(sc.dir, sc.Baseline.File)
+}
+
+//DrainWait return how long unconfirmed invokes are waited for after the last job
+func (sc *Scenario) DrainWait() (time.Duration, error) {
+	if sc.DrainTimeout == "" {
+		return 60 * time.Second, nil
+	}
+	d, err := time.ParseDuration(sc.DrainTimeout)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid drain_timeout %q", sc.DrainTimeout)
+	}
+	return d, nil
+}
+
+//what the stages of a scenario target
+const (
+	ProfileConcurrency = "concurrency"
+	ProfileRate        = "rate"
+)
+
+//stages convert the stage configs to runner stages
+func (sc *Scenario) stages() ([]runner.Stage, error) {
+	switch sc.Profile {
+	case "", ProfileConcurrency, ProfileRate:
+	default:
+		return nil, fmt.Errorf("unknown profile %q", sc.Profile)
+	}
+
+	stages := make([]runner.Stage, 0, len(sc.Stages))
+	var peak float64
+	for i, cfg := range sc.Stages {
+		st := runner.Stage{Name: cfg.Name, To: cfg.To}
+		if st.Name == "" {
+			st.Name = fmt.Sprintf("stage%d", i+1)
+		}
+		d, err := time.ParseDuration(cfg.Duration)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("stage %s: invalid duration %q", st.Name, cfg.Duration)
+		}
+		st.Duration = d
+		switch {
+		case cfg.From != nil:
+			st.From = *cfg.From
+		case i > 0:
+			st.From = stages[i-1].To
+		default:
+			st.From = cfg.To
+		}
+		if st.From < 0 || st.To < 0 {
+			return nil, fmt.Errorf("stage %s: target must not be negative", st.Name)
+		}
+		peak = math.Max(peak, math.Max(st.From, st.To))
+		stages = append(stages, st)
+	}
+	if len(stages) > 0 && sc.Profile != ProfileRate && peak < 1 {
+		return nil, errors.New("stages need a concurrency of at least 1")
+	}
+	return stages, nil
+}
+
+//Jobs generate the jobs of the scenario into a channel, the channel is closed after the last job.
+//Jobs are generated endlessly when job_count is 0, until stop is closed.
+func (sc *Scenario) Jobs(stop <-chan struct{}) <-chan *job.Job {
+	ch := make(chan *job.Job, 100)
+	go func() {
+		defer close(ch)
+		for i := 1 + sc.Offset; sc.JobCount == 0 || i <= sc.JobCount+sc.Offset; i++ {
+			jb, err := sc.Job(i)
+			if err == generator.ErrExhausted {
+				fmt.Printf("feeder ran out of rows after %d jobs\n", i-1-sc.Offset)
+				return
+			}
+			if err != nil {
+				fmt.Printf("fail to build job %d:%v\n", i, err)
+				return
+			}
+			select {
+			case ch <- jb:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,145 @@
+package slo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/shimron/stressingtool/runner"
+)
+
+//metrics compared to the baseline besides the ones with a tolerance
+var compared = []string{"tps", "success_rate", "failed_count", "execution.p50", "execution.p99", "confirm.p50", "confirm.p99"}
+
+//defaultTolerance is the regression allowed for a metric without tolerance
+const defaultTolerance = "10%"
+
+//Baseline compare a run to the summary of a previous one
+type Baseline struct {
+	//File is a summary saved by a previous run, e.g. create_user_summary.json
+	File string `yaml:"file" json:"file"`
+	//Tolerances are the regressions allowed by metric, relative like 10% or absolute like 200ms, 10% by default
+	Tolerances map[string]string `yaml:"tolerances" json:"tolerances"`
+}
+
+//Diff is the change of one metric between the baseline and the run
+type Diff struct {
+	Metric    string
+	Baseline  string
+	Current   string
+	Change    string
+	Tolerance string
+	OK        bool
+}
+
+//Validate check the metrics and the tolerances
+func (b *Baseline) Validate() error {
+	for name, tol := range b.Tolerances {
+		m, err := lookup(name)
+		if err != nil {
+			return err
+		}
+		if _, err := tolerance(m, tol, 0); err != nil {
+			return fmt.Errorf("baseline tolerance of %s:%v", name, err)
+		}
+	}
+	return nil
+}
+
+//LoadSummary read a summary saved as json
+func LoadSummary(path string) (*runner.Summary, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &runner.Summary{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("fail to parse summary %s:%v", path, err)
+	}
+	if s.Total == nil {
+		return nil, fmt.Errorf("%s is not a summary", path)
+	}
+	return s, nil
+}
+
+//Compare compare the key metrics of the run to the baseline, a metric fails when it regressed beyond its tolerance
+func (b *Baseline) Compare(baseline *runner.Summary, current *runner.Summary) ([]Diff, error) {
+	names := append([]string(nil), compared...)
+	var extra []string
+	for name := range b.Tolerances {
+		if !contains(names, name) {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	names = append(names, extra...)
+
+	diffs := make([]Diff, 0, len(names))
+	for _, name := range names {
+		m, err := lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		tol, ok := b.Tolerances[name]
+		if !ok {
+			tol = defaultTolerance
+		}
+		base, cur := m.value(baseline), m.value(current)
+		allowed, err := tolerance(m, tol, base)
+		if err != nil {
+			return nil, err
+		}
+
+		d := Diff{Metric: name, Baseline: m.format(base), Current: m.format(cur), Tolerance: tol}
+		if base != 0 {
+			d.Change = fmt.Sprintf("%+.1f%%", (cur-base)*100/math.Abs(base))
+		} else {
+			d.Change = fmt.Sprintf("%+g", cur-base)
+		}
+		if m.higherIsBetter {
+			d.OK = cur >= base-allowed
+		} else {
+			d.OK = cur <= base+allowed
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
+
+//tolerance return the regression allowed from base, tol is relative when it ends with %
+func tolerance(m metric, tol string, base float64) (float64, error) {
+	tol = strings.TrimSpace(tol)
+	if strings.HasSuffix(tol, "%") {
+		v, err := parseValue(metric{}, tol)
+		return math.Abs(base) * v / 100, err
+	}
+	return parseValue(m, tol)
+}
+
+//PrintDiff print the diff table and return false if any metric regressed beyond its tolerance
+func PrintDiff(diffs []Diff) bool {
+	ok := true
+	fmt.Println("********Baseline*******")
+	fmt.Printf("%-16s %-14s %-14s %-10s %-10s %s\n", "metric", "baseline", "current", "change", "tolerance", "status")
+	for _, d := range diffs {
+		status := "ok"
+		if !d.OK {
+			status = "REGRESSED"
+			ok = false
+		}
+		fmt.Printf("%-16s %-14s %-14s %-10s %-10s %s\n", d.Metric, d.Baseline, d.Current, d.Change, d.Tolerance, status)
+	}
+	return ok
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
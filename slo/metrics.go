@@ -0,0 +1,69 @@
+package slo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shimron/stressingtool/runner"
+)
+
+//metric is a value read from the summary of a run
+type metric struct {
+	value func(s *runner.Summary) float64
+	//higherIsBetter tell which way is a regression
+	higherIsBetter bool
+	//seconds metrics accept durations like 3s as thresholds
+	seconds bool
+}
+
+var metrics = map[string]metric{
+	"tps":            {value: func(s *runner.Summary) float64 { return s.Throughput }, higherIsBetter: true},
+	"success_rate":   {value: func(s *runner.Summary) float64 { return s.Total.SuccessRate }, higherIsBetter: true},
+	"job_count":      {value: func(s *runner.Summary) float64 { return float64(s.Total.JobCount) }, higherIsBetter: true},
+	"failed_count":   {value: func(s *runner.Summary) float64 { return float64(s.Total.FailedCount) }},
+	"rejected_count": {value: func(s *runner.Summary) float64 { return float64(failureClass(s, "rejected")) }},
+}
+
+func init() {
+	for _, name := range []string{"execution", "confirm"} {
+		latency := func(s *runner.Summary) runner.Latency { return s.Total.Execution }
+		if name == "confirm" {
+			latency = func(s *runner.Summary) runner.Latency { return s.Total.Confirm }
+		}
+		metrics[name+".min"] = metric{value: func(s *runner.Summary) float64 { return latency(s).Min }, seconds: true}
+		metrics[name+".mean"] = metric{value: func(s *runner.Summary) float64 { return latency(s).Mean }, seconds: true}
+		metrics[name+".max"] = metric{value: func(s *runner.Summary) float64 { return latency(s).Max }, seconds: true}
+		for _, p := range []string{"p50", "p90", "p95", "p99", "p99.9"} {
+			p := p
+			metrics[name+"."+p] = metric{value: func(s *runner.Summary) float64 { return latency(s).Percentiles[p] }, seconds: true}
+		}
+	}
+}
+
+func failureClass(s *runner.Summary, class string) int {
+	if s.Failures == nil {
+		return 0
+	}
+	return s.Failures.Classes[class]
+}
+
+func lookup(name string) (metric, error) {
+	m, ok := metrics[name]
+	if !ok {
+		names := make([]string, 0, len(metrics))
+		for n := range metrics {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return m, fmt.Errorf("unknown metric %q, known metrics are %s", name, strings.Join(names, ", "))
+	}
+	return m, nil
+}
+
+func (m metric) format(v float64) string {
+	if m.seconds {
+		return fmt.Sprintf("%.4fs", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
@@ -0,0 +1,115 @@
+package slo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shimron/stressingtool/runner"
+)
+
+//Threshold is one service level objective of a run, e.g. confirm.p99 with max 3s.
+//Latencies take durations or seconds, rates take percents.
+type Threshold struct {
+	Metric string `yaml:"metric" json:"metric"`
+	Min    string `yaml:"min" json:"min"`
+	Max    string `yaml:"max" json:"max"`
+}
+
+//Check is the outcome of one threshold or baseline comparison
+type Check struct {
+	Metric string
+	Value  string
+	Limit  string
+	OK     bool
+}
+
+//Validate check the metrics and the values of the thresholds
+func Validate(thresholds []Threshold) error {
+	for _, t := range thresholds {
+		if _, _, err := t.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t Threshold) compile() (m metric, bounds [2]*float64, err error) {
+	if m, err = lookup(t.Metric); err != nil {
+		return
+	}
+	if t.Min == "" && t.Max == "" {
+		err = fmt.Errorf("slo %s: min or max is required", t.Metric)
+		return
+	}
+	for i, s := range []string{t.Min, t.Max} {
+		if s == "" {
+			continue
+		}
+		v, perr := parseValue(m, s)
+		if perr != nil {
+			err = fmt.Errorf("slo %s:%v", t.Metric, perr)
+			return
+		}
+		bounds[i] = &v
+	}
+	return
+}
+
+//Evaluate check every threshold against the summary of a run
+func Evaluate(s *runner.Summary, thresholds []Threshold) ([]Check, error) {
+	checks := make([]Check, 0, len(thresholds))
+	for _, t := range thresholds {
+		m, bounds, err := t.compile()
+		if err != nil {
+			return nil, err
+		}
+		v := m.value(s)
+		c := Check{Metric: t.Metric, Value: m.format(v), OK: true}
+		var limits []string
+		if min := bounds[0]; min != nil {
+			limits = append(limits, ">= "+m.format(*min))
+			c.OK = c.OK && v >= *min
+		}
+		if max := bounds[1]; max != nil {
+			limits = append(limits, "<= "+m.format(*max))
+			c.OK = c.OK && v <= *max
+		}
+		c.Limit = strings.Join(limits, " and ")
+		checks = append(checks, c)
+	}
+	return checks, nil
+}
+
+//parseValue parse a threshold: a duration or seconds for latencies, a number with an optional % otherwise
+func parseValue(m metric, s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if m.seconds {
+		if d, err := time.ParseDuration(s); err == nil {
+			return d.Seconds(), nil
+		}
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return 0, errors.New("invalid value " + s)
+	}
+	return v, nil
+}
+
+//Print print the checks as a table and return false if any of them failed
+func Print(title string, checks []Check) bool {
+	ok := true
+	fmt.Printf("********%s*******\n", title)
+	fmt.Printf("%-16s %-14s %-28s %s\n", "metric", "value", "limit", "status")
+	for _, c := range checks {
+		status := "ok"
+		if !c.OK {
+			status = "FAILED"
+			ok = false
+		}
+		fmt.Printf("%-16s %-14s %-28s %s\n", c.Metric, c.Value, c.Limit, status)
+	}
+	return ok
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/shimron/stressingtool/scenario"
+)
+
+var validateCmd = &command{
+	name:    "validate",
+	usage:   "<scenario>...",
+	summary: "check scenario files without sending any traffic",
+}
+
+func init() {
+	validateCmd.run = runValidate
+}
+
+func runValidate(args []string) int {
+	fs := newFlagSet(validateCmd)
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	code := 0
+	for _, path := range fs.Args() {
+		sc, err := scenario.Load(path)
+		if err != nil {
+			fmt.Printf("%s: %v\n", path, err)
+			code = 1
+			continue
+		}
+		jb, err := sc.Job(sc.Offset + 1)
+		if err == nil && jb.Bind != nil {
+			jb, err = jb.Bind(0)
+		}
+		if err != nil {
+			fmt.Printf("%s: %v\n", path, err)
+			code = 1
+			continue
+		}
+		kind := mode(jb.Command.IsInvoke)
+		if len(sc.Workflow) != 0 {
+			kind = fmt.Sprintf("%d step workflow", len(sc.Workflow))
+		}
+		if len(sc.Mix) != 0 {
+			kind = fmt.Sprintf("%d operation mix", len(sc.Mix))
+		}
+		load := fmt.Sprintf("at concurrency %d", sc.ConcurrencyNum)
+		if sc.Rate > 0 {
+			load = fmt.Sprintf("at %.2f jobs/s", sc.Rate)
+		}
+		if len(sc.Stages) > 0 {
+			load = fmt.Sprintf("in %d stages", len(sc.Stages))
+		}
+		if s := sc.Saturation; s != nil {
+			load = fmt.Sprintf("searching saturation from %.2f to %.2f by %.2f", s.Start, s.Max, s.Step)
+		}
+		count := fmt.Sprintf("%d jobs", sc.JobCount)
+		if sc.JobCount == 0 {
+			count = "endless jobs"
+		}
+		if sc.Duration != "" {
+			count += " for " + sc.Duration
+		}
+		fmt.Printf("%s: ok, %s of %s %s, seed %d, first args:%q\n",
+			path, count, kind, load, sc.Seed, jb.Command.Args)
+	}
+	return code
+}
+
+func mode(isInvoke bool) string {
+	if isInvoke {
+		return "invoke"
+	}
+	return "query"
+}
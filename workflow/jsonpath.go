@@ -0,0 +1,89 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//jsonPath is a compiled subset of JSONPath: $, .field, ['field'] and [index]
+type jsonPath struct {
+	expr  string
+	steps []interface{} //string for a field, int for an index
+}
+
+func parseJSONPath(expr string) (*jsonPath, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("json path %q must start with $", expr)
+	}
+	p := &jsonPath{expr: expr}
+	rest := expr[1:]
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("json path %q has an empty field", expr)
+			}
+			p.steps = append(p.steps, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("json path %q has an unclosed [", expr)
+			}
+			key := rest[1:end]
+			rest = rest[end+1:]
+			if len(key) >= 2 && (key[0] == '\'' || key[0] == '"') && key[len(key)-1] == key[0] {
+				p.steps = append(p.steps, key[1:len(key)-1])
+				continue
+			}
+			i, err := strconv.Atoi(key)
+			if err != nil {
+				return nil, fmt.Errorf("json path %q has an invalid index %q", expr, key)
+			}
+			p.steps = append(p.steps, i)
+		default:
+			return nil, fmt.Errorf("json path %q is invalid at %q", expr, rest)
+		}
+	}
+	return p, nil
+}
+
+//eval return the selected value of doc, strings are returned as is and any other value as json
+func (p *jsonPath) eval(doc string) (string, error) {
+	var v interface{}
+	d := json.NewDecoder(strings.NewReader(doc))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return "", fmt.Errorf("result is not json:%v", err)
+	}
+	for _, step := range p.steps {
+		switch s := step.(type) {
+		case string:
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("%s: %q is not an object field", p.expr, s)
+			}
+			if v, ok = m[s]; !ok {
+				return "", fmt.Errorf("%s: field %q not found", p.expr, s)
+			}
+		case int:
+			a, ok := v.([]interface{})
+			if !ok || s < 0 || s >= len(a) {
+				return "", fmt.Errorf("%s: index %d out of range", p.expr, s)
+			}
+			v = a[s]
+		}
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -0,0 +1,94 @@
+package workflow
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		expr  string
+		steps []interface{}
+		err   bool
+	}{
+		{expr: "$", steps: nil},
+		{expr: "$.user", steps: []interface{}{"user"}},
+		{expr: "$.user.id", steps: []interface{}{"user", "id"}},
+		{expr: "$.users[2].id", steps: []interface{}{"users", 2, "id"}},
+		{expr: "$['user']['first name']", steps: []interface{}{"user", "first name"}},
+		{expr: `$["user"].id`, steps: []interface{}{"user", "id"}},
+		{expr: "$[0][1]", steps: []interface{}{0, 1}},
+		{expr: "user.id", err: true},
+		{expr: "$.", err: true},
+		{expr: "$..id", err: true},
+		{expr: "$.users[0", err: true},
+		{expr: "$.users[x]", err: true},
+		{expr: "$user", err: true},
+	}
+	for _, tt := range tests {
+		p, err := parseJSONPath(tt.expr)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseJSONPath(%q) = %v, want an error", tt.expr, p.steps)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseJSONPath(%q) failed:%v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(p.steps, tt.steps) {
+			t.Errorf("parseJSONPath(%q) = %#v, want %#v", tt.expr, p.steps, tt.steps)
+		}
+	}
+}
+
+func TestJSONPathEval(t *testing.T) {
+	const doc = `{"user":{"id":"u1","age":42,"score":1.50,"admin":false,"tags":["a","b"],"address":null},"users":[{"id":"u2"},{"id":"u3"}]}`
+	tests := []struct {
+		expr string
+		doc  string
+		want string
+		err  bool
+	}{
+		{expr: "$.user.id", doc: doc, want: "u1"},
+		{expr: "$.user.age", doc: doc, want: "42"},
+		//numbers are kept as written
+		{expr: "$.user.score", doc: doc, want: "1.50"},
+		{expr: "$.user.admin", doc: doc, want: "false"},
+		{expr: "$.user.address", doc: doc, want: "null"},
+		{expr: "$.user.tags", doc: doc, want: `["a","b"]`},
+		{expr: "$.user.tags[1]", doc: doc, want: "b"},
+		{expr: "$.users[0].id", doc: doc, want: "u2"},
+		{expr: "$['users'][1]['id']", doc: doc, want: "u3"},
+		{expr: "$", doc: `"plain"`, want: "plain"},
+		{expr: "$[0]", doc: `[7,8]`, want: "7"},
+		{expr: "$.user.email", doc: doc, err: true},
+		{expr: "$.users[2].id", doc: doc, err: true},
+		{expr: "$.users[-1]", doc: doc, err: true},
+		{expr: "$.user[0]", doc: doc, err: true},
+		{expr: "$.users.id", doc: doc, err: true},
+		{expr: "$.user.id.value", doc: doc, err: true},
+		{expr: "$.user", doc: "not json", err: true},
+	}
+	for _, tt := range tests {
+		p, err := parseJSONPath(tt.expr)
+		if err != nil {
+			t.Fatalf("parseJSONPath(%q) failed:%v", tt.expr, err)
+		}
+		got, err := p.eval(tt.doc)
+		if tt.err {
+			if err == nil {
+				t.Errorf("eval %s = %q, want an error", tt.expr, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("eval %s failed:%v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("eval %s = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
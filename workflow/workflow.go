@@ -0,0 +1,225 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shimron/stressingtool/chaincode"
+	"github.com/shimron/stressingtool/generator"
+	"github.com/shimron/stressingtool/job"
+)
+
+//special extract sources, anything else is a json path into the result message
+const (
+	//SourceTxID is the txid of an invoke
+	SourceTxID = "txid"
+	//SourceMessage is the whole result message of a query
+	SourceMessage = "message"
+)
+
+const defaultWaitTimeout = 30 * time.Second
+
+//StepConfig describes one chaincode call of a workflow
+type StepConfig struct {
+	Name     string   `yaml:"name" json:"name"`
+	Function string   `yaml:"function" json:"function"`
+	Args     []string `yaml:"args" json:"args"`
+	IsInvoke bool     `yaml:"invoke" json:"invoke"`
+	//Set render variables before the args of the step, e.g. email: "{{email}}"
+	Set map[string]string `yaml:"set" json:"set"`
+	//Extract read variables from the result of the step: txid, message or a json path like $.user.id
+	Extract map[string]string `yaml:"extract" json:"extract"`
+	//WaitCommit wait until the invoke is written to ledger before the next step
+	WaitCommit bool `yaml:"wait_commit" json:"wait_commit"`
+	//WaitTimeout abort the workflow when the invoke is not written to ledger in time, 30s by default
+	WaitTimeout string `yaml:"wait_timeout" json:"wait_timeout"`
+	//Tags are added to the tags of the workflow for the jobs of the step
+	Tags []string `yaml:"tags" json:"tags"`
+}
+
+type step struct {
+	StepConfig
+	args        *generator.Template
+	setNames    []string
+	set         *generator.Template
+	extract     map[string]*jsonPath //nil for txid and message
+	waitTimeout time.Duration
+}
+
+//Workflow is a chain of steps run in order by one virtual user,
+//variables set or extracted by a step are available to all later steps as {{.Vars.<name>}}
+type Workflow struct {
+	Name string
+	URL  string
+	CCID string
+	//Tags are set on the jobs of every step
+	Tags []string
+	//Transport send the requests of every step, chaincode.DefaultClient when it is nil
+	Transport chaincode.Transport
+	//Retry retry the failed calls of every step
+	Retry *job.RetryPolicy
+	steps []*step
+	//templates are not safe for concurrent use and steps are rendered by many workers
+	lock sync.Mutex
+}
+
+//New compile the steps of a workflow, every step has its own random source derived from seed
+func New(name string, url string, ccid string, configs []StepConfig, seed int64) (*Workflow, error) {
+	if len(configs) == 0 {
+		return nil, errors.New("workflow has no steps")
+	}
+	w := &Workflow{Name: name, URL: url, CCID: ccid}
+	for i, cfg := range configs {
+		if cfg.Name == "" {
+			cfg.Name = fmt.Sprintf("step%d", i+1)
+		}
+		if cfg.Function == "" {
+			return nil, fmt.Errorf("step %s: function is required", cfg.Name)
+		}
+		st := &step{StepConfig: cfg, extract: make(map[string]*jsonPath), waitTimeout: defaultWaitTimeout}
+
+		var err error
+		if cfg.WaitTimeout != "" {
+			if st.waitTimeout, err = time.ParseDuration(cfg.WaitTimeout); err != nil {
+				return nil, fmt.Errorf("step %s: invalid wait_timeout:%v", cfg.Name, err)
+			}
+		}
+		if cfg.WaitCommit && !cfg.IsInvoke {
+			return nil, fmt.Errorf("step %s: only invoke can wait_commit", cfg.Name)
+		}
+		if st.args, err = generator.NewTemplate(generator.SubSeed(seed, fmt.Sprintf("step/%d/args", i)), cfg.Args); err != nil {
+			return nil, fmt.Errorf("step %s:%v", cfg.Name, err)
+		}
+		for name := range cfg.Set {
+			st.setNames = append(st.setNames, name)
+		}
+		sort.Strings(st.setNames)
+		setTemplates := make([]string, 0, len(st.setNames))
+		for _, name := range st.setNames {
+			setTemplates = append(setTemplates, cfg.Set[name])
+		}
+		if st.set, err = generator.NewTemplate(generator.SubSeed(seed, fmt.Sprintf("step/%d/set", i)), setTemplates); err != nil {
+			return nil, fmt.Errorf("step %s set:%v", cfg.Name, err)
+		}
+		for name, src := range cfg.Extract {
+			switch src {
+			case SourceTxID:
+				if !cfg.IsInvoke {
+					return nil, fmt.Errorf("step %s: only invoke has a txid to extract", cfg.Name)
+				}
+				st.extract[name] = nil
+			case SourceMessage:
+				st.extract[name] = nil
+			default:
+				if st.extract[name], err = parseJSONPath(src); err != nil {
+					return nil, fmt.Errorf("step %s:%v", cfg.Name, err)
+				}
+			}
+		}
+		w.steps = append(w.steps, st)
+	}
+	return w, nil
+}
+
+//Start return the first step of the seq-th run of the workflow, vars are the initial variables.
+//Every step is sent as identity, the secureContext of the run.
+func (w *Workflow) Start(seq int, identity string, vars map[string]string) (*job.Job, error) {
+	in := &instance{wf: w, seq: seq, identity: identity, vars: make(map[string]string, len(vars))}
+	for k, v := range vars {
+		in.vars[k] = v
+	}
+	return in.job()
+}
+
+//DryRun render every step once, variables extracted from results are replaced by placeholders
+func (w *Workflow) DryRun(seq int, vars map[string]string) error {
+	in := &instance{wf: w, seq: seq, vars: make(map[string]string, len(vars))}
+	for k, v := range vars {
+		in.vars[k] = v
+	}
+	for in.step < len(w.steps) {
+		if _, err := in.job(); err != nil {
+			return err
+		}
+		for name := range w.steps[in.step].Extract {
+			in.vars[name] = "<" + name + ">"
+		}
+		in.step++
+	}
+	return nil
+}
+
+//instance is one run of a workflow by a virtual user
+type instance struct {
+	wf   *Workflow
+	seq  int
+	step int
+	vars map[string]string
+	//identity is the secureContext of every step
+	identity string
+}
+
+//Next extract the variables of the finished step and return the job of the next one
+func (in *instance) Next(js *job.JobStat) (*job.Job, error) {
+	st := in.wf.steps[in.step]
+	for name, p := range st.extract {
+		switch {
+		case st.Extract[name] == SourceTxID:
+			in.vars[name] = js.TXID
+		case p == nil:
+			in.vars[name] = js.Result
+		default:
+			v, err := p.eval(js.Result)
+			if err != nil {
+				return nil, fmt.Errorf("fail to extract %s:%v", name, err)
+			}
+			in.vars[name] = v
+		}
+	}
+	in.step++
+	if in.step >= len(in.wf.steps) {
+		return nil, nil
+	}
+	return in.job()
+}
+
+//job render the current step
+func (in *instance) job() (*job.Job, error) {
+	st := in.wf.steps[in.step]
+	in.wf.lock.Lock()
+	defer in.wf.lock.Unlock()
+
+	values, err := st.set.Render(generator.Data{Seq: in.seq, Vars: in.vars})
+	if err != nil {
+		return nil, fmt.Errorf("step %s set:%v", st.Name, err)
+	}
+	for i, name := range st.setNames {
+		in.vars[name] = values[i]
+	}
+	rendered, err := st.args.Render(generator.Data{Seq: in.seq, Vars: in.vars})
+	if err != nil {
+		return nil, fmt.Errorf("step %s:%v", st.Name, err)
+	}
+
+	args := make([]string, 0, len(rendered)+1)
+	args = append(args, st.Function)
+	args = append(args, rendered...)
+	jb := job.NewJob(fmt.Sprintf("%s_job_%d_%s", in.wf.Name, in.seq, st.Name), job.ChainCodeCommand{
+		URL:           in.wf.URL,
+		CCID:          in.wf.CCID,
+		Args:          args,
+		IsInvoke:      st.IsInvoke,
+		SecureContext: in.identity,
+		Transport:     in.wf.Transport,
+	})
+	jb.Operation = st.Name
+	jb.Flow = in
+	jb.WaitCommit = st.WaitCommit
+	jb.WaitTimeout = st.waitTimeout
+	jb.Tags = append(append([]string(nil), in.wf.Tags...), st.Tags...)
+	jb.Retry = in.wf.Retry
+	return jb, nil
+}